@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/BabichevDima/goServer/internal/auth"
+	"github.com/google/uuid"
+)
+
+// Scopes limit what a request authenticated with an API key or an OAuth
+// access token may do. Sessions started by logging in with a password have
+// every scope.
+const (
+	// scopeRead allows GET requests to authenticated endpoints.
+	scopeRead = "read"
+	// scopeWrite allows changes that no narrower scope covers, such as
+	// likes, follows, bookmarks, blocks and mutes.
+	scopeWrite = "write"
+	// scopeChirpsWrite allows creating and editing chirps and uploading
+	// their media.
+	scopeChirpsWrite = "chirps:write"
+	// scopeChirpsDelete allows deleting chirps.
+	scopeChirpsDelete = "chirps:delete"
+	// scopeSession is never granted, so endpoints that require it, like
+	// managing API keys, webhooks and the account itself, are only
+	// available to password sessions.
+	scopeSession = "session"
+)
+
+// grantableScopes are the scopes an API key or OAuth client can be given.
+var grantableScopes = []string{scopeRead, scopeWrite, scopeChirpsWrite, scopeChirpsDelete}
+
+// credential is who a request acts for and what it may do.
+type credential struct {
+	UserID uuid.UUID
+	// Scopes is nil for password sessions, which may do anything.
+	Scopes []string
+}
+
+func (c credential) allows(scope string) bool {
+	return c.Scopes == nil || slices.Contains(c.Scopes, scope)
+}
+
+// authenticate resolves the request's "Authorization: Bearer <token>" or
+// "Authorization: ApiKey <key>" header.
+func (cfg *apiConfig) authenticate(r *http.Request) (credential, *APIError) {
+	if auth.IsAPIKeyAuth(r.Header) {
+		key, err := auth.GetAPIKey(r.Header)
+		if err != nil {
+			return credential{}, newAPIError(http.StatusUnauthorized, ErrCodeInvalidToken, "Invalid API key")
+		}
+		apiKey, err := cfg.DB.GetAPIKeyByHash(r.Context(), auth.HashAPIKey(key))
+		if errors.Is(err, sql.ErrNoRows) {
+			return credential{}, newAPIError(http.StatusUnauthorized, ErrCodeInvalidToken, "Invalid API key")
+		}
+		if err != nil {
+			return credential{}, dbError(err, "Failed to check API key")
+		}
+		if err := cfg.DB.TouchAPIKey(r.Context(), apiKey.ID); err != nil {
+			log.Printf("Failed to record use of API key %s: %v", apiKey.ID, err)
+		}
+		return credential{UserID: apiKey.UserID, Scopes: apiKey.Scopes}, nil
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return credential{}, newAPIError(http.StatusUnauthorized, ErrCodeUnauthorized, "Authentication required")
+	}
+	userID, scopes, err := auth.ValidateScopedJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		return credential{}, newAPIError(http.StatusUnauthorized, ErrCodeInvalidToken, "Invalid access token")
+	}
+	if apiErr := cfg.requireActiveUser(r.Context(), userID); apiErr != nil {
+		return credential{}, apiErr
+	}
+	if len(scopes) == 0 {
+		return credential{UserID: userID}, nil
+	}
+	return credential{UserID: userID, Scopes: scopes}, nil
+}
+
+// requireActiveUser fails if the account an access token was issued for
+// has been deleted or is pending deletion. Access tokens can't be revoked,
+// so the account is checked on every request.
+func (cfg *apiConfig) requireActiveUser(ctx context.Context, userID uuid.UUID) *APIError {
+	user, err := cfg.DB.GetUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return newAPIError(http.StatusUnauthorized, ErrCodeInvalidToken, "Invalid access token")
+	}
+	if err != nil {
+		return dbError(err, "Failed to check account")
+	}
+	if user.DeletedAt.Valid {
+		return newAPIError(http.StatusUnauthorized, ErrCodeInvalidToken, "Account is scheduled for deletion, log in to restore it")
+	}
+	return nil
+}
+
+// middlewareAuthScope works like middlewareAuth but requires scope rather
+// than the one implied by the request method. It responds with 403 if the
+// request's API key or OAuth token lacks it.
+func (cfg *apiConfig) middlewareAuthScope(scope string, next authedHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cred, apiErr := cfg.authenticate(r)
+		if apiErr != nil {
+			respondWithAPIError(w, apiErr)
+			return
+		}
+
+		required := scope
+		if required == "" {
+			required = methodScope(r.Method)
+		}
+		if !cred.allows(required) {
+			respondWithAPIError(w, &APIError{
+				Status:  http.StatusForbidden,
+				Code:    ErrCodeInsufficientScope,
+				Message: "Insufficient scope",
+				Details: map[string]any{"scope": required},
+			})
+			return
+		}
+
+		next(w, r, cred.UserID)
+	})
+}
+
+// methodScope is the scope required by endpoints that don't name one:
+// read for safe methods and write for everything else.
+func methodScope(method string) string {
+	if method == http.MethodGet || method == http.MethodHead {
+		return scopeRead
+	}
+	return scopeWrite
+}
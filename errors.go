@@ -0,0 +1,153 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+// Stable machine-readable error codes. Clients should switch on these
+// instead of the human readable detail, which may change at any time.
+const (
+	ErrCodeInvalidPayload    = "invalid_payload"
+	ErrCodeValidation        = "validation_failed"
+	ErrCodeUnauthorized      = "unauthorized"
+	ErrCodeInvalidToken      = "invalid_token"
+	ErrCodeForbidden         = "forbidden"
+	ErrCodeInsufficientScope = "insufficient_scope"
+	ErrCodeNotFound          = "not_found"
+	ErrCodeUniqueViolation   = "unique_violation"
+	ErrCodeFKViolation       = "fk_violation"
+	ErrCodePayloadTooLarge   = "payload_too_large"
+	ErrCodeUnsupportedMedia  = "unsupported_media_type"
+	ErrCodeInternal          = "internal_error"
+)
+
+// Postgres error codes we translate into API errors.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+)
+
+// APIError is an error that knows how to present itself to API clients.
+// It is rendered as an RFC 7807 problem+json document by respondWithAPIError.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Details map[string]any
+	Err     error
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// newAPIError creates an APIError with the given HTTP status, code and message.
+func newAPIError(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// problem is the RFC 7807 representation of an APIError.
+type problem struct {
+	Type    string         `json:"type"`
+	Title   string         `json:"title"`
+	Status  int            `json:"status"`
+	Detail  string         `json:"detail,omitempty"`
+	Code    string         `json:"code"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// dbError translates an error returned by the database layer into an APIError.
+// Unique and foreign key violations are recognised through the underlying
+// *pq.Error, sql.ErrNoRows becomes a 404, and anything else is reported as an
+// internal error with the given message.
+func dbError(err error, message string) *APIError {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pqUniqueViolation:
+			return &APIError{
+				Status:  http.StatusConflict,
+				Code:    ErrCodeUniqueViolation,
+				Message: message,
+				Details: map[string]any{"constraint": pqErr.Constraint},
+				Err:     err,
+			}
+		case pqForeignKeyViolation:
+			return &APIError{
+				Status:  http.StatusConflict,
+				Code:    ErrCodeFKViolation,
+				Message: message,
+				Details: map[string]any{"constraint": pqErr.Constraint},
+				Err:     err,
+			}
+		}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return &APIError{Status: http.StatusNotFound, Code: ErrCodeNotFound, Message: message, Err: err}
+	}
+	return &APIError{Status: http.StatusInternalServerError, Code: ErrCodeInternal, Message: message, Err: err}
+}
+
+// Unique constraints on users, named by Postgres' defaults.
+const (
+	usersEmailKey  = "users_email_key"
+	usersHandleKey = "users_handle_key"
+)
+
+// userConflictError works like dbError for writes to users, but says which
+// field is already taken when err violates the email or handle constraint.
+func userConflictError(err error, message string) *APIError {
+	apiErr := dbError(err, message)
+	if apiErr.Code != ErrCodeUniqueViolation {
+		return apiErr
+	}
+	switch apiErr.Details["constraint"] {
+	case usersEmailKey:
+		apiErr.Message = "Email already in use"
+		apiErr.Details["field"] = "email"
+	case usersHandleKey:
+		apiErr.Message = "Handle already taken"
+		apiErr.Details["field"] = "handle"
+	}
+	return apiErr
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation
+}
+
+// respondWithAPIError renders err as an application/problem+json response.
+// Errors that are not an *APIError are reported as a generic internal error
+// so that implementation details never leak to the client.
+func respondWithAPIError(w http.ResponseWriter, err error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		apiErr = &APIError{Status: http.StatusInternalServerError, Code: ErrCodeInternal, Message: "Internal server error", Err: err}
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(problem{
+		Type:    "about:blank",
+		Title:   http.StatusText(apiErr.Status),
+		Status:  apiErr.Status,
+		Detail:  apiErr.Message,
+		Code:    apiErr.Code,
+		Details: apiErr.Details,
+	})
+}
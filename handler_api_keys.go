@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BabichevDima/goServer/internal/auth"
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxAPIKeysPerUser   = 20
+	maxAPIKeyNameLength = 100
+)
+
+// APIKey describes an API key without revealing it. Key is only returned
+// when the key is created.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Key        string     `json:"key,omitempty"`
+}
+
+func apiKeyFromDB(dbKey database.ApiKey) APIKey {
+	key := APIKey{
+		ID:        dbKey.ID,
+		CreatedAt: dbKey.CreatedAt,
+		Name:      dbKey.Name,
+		Prefix:    dbKey.Prefix,
+		Scopes:    dbKey.Scopes,
+	}
+	if dbKey.LastUsedAt.Valid {
+		key.LastUsedAt = &dbKey.LastUsedAt.Time
+	}
+	return key
+}
+
+// validateScopes checks requested scopes and returns them without
+// duplicates.
+func validateScopes(scopes []string) ([]string, *APIError) {
+	if len(scopes) == 0 {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "At least one scope is required",
+			Details: map[string]any{"scopes": grantableScopes},
+		}
+	}
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(grantableScopes, scope) {
+			return nil, &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    ErrCodeValidation,
+				Message: "Unknown scope",
+				Details: map[string]any{"scope": scope, "scopes": grantableScopes},
+			}
+		}
+		if !slices.Contains(unique, scope) {
+			unique = append(unique, scope)
+		}
+	}
+	return unique, nil
+}
+
+// handlerCreateAPIKey creates an API key for the authenticated user. The
+// key itself is only included in this response.
+func (cfg *apiConfig) handlerCreateAPIKey(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	type parameters struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
+		return
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPIKeyNameLength {
+		respondWithAPIError(w, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "API key name is required and must be short",
+			Details: map[string]any{"max_length": maxAPIKeyNameLength},
+		})
+		return
+	}
+	scopes, apiErr := validateScopes(params.Scopes)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	count, err := cfg.DB.CountAPIKeys(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to create API key"))
+		return
+	}
+	if count >= maxAPIKeysPerUser {
+		respondWithAPIError(w, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "Too many API keys",
+			Details: map[string]any{"limit": maxAPIKeysPerUser},
+		})
+		return
+	}
+
+	key, prefix, err := auth.MakeAPIKey()
+	if err != nil {
+		respondWithAPIError(w, &APIError{Status: http.StatusInternalServerError, Code: ErrCodeInternal, Message: "Failed to create API key", Err: err})
+		return
+	}
+
+	dbKey, err := cfg.DB.CreateAPIKey(r.Context(), database.CreateAPIKeyParams{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		HashedKey: auth.HashAPIKey(key),
+		Scopes:    scopes,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to create API key"))
+		return
+	}
+
+	resp := apiKeyFromDB(dbKey)
+	resp.Key = key
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+// handlerGetAPIKeys lists the authenticated user's active API keys.
+func (cfg *apiConfig) handlerGetAPIKeys(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	dbKeys, err := cfg.DB.GetAPIKeys(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get API keys"))
+		return
+	}
+
+	keys := make([]APIKey, len(dbKeys))
+	for i, dbKey := range dbKeys {
+		keys[i] = apiKeyFromDB(dbKey)
+	}
+	respondWithJSON(w, http.StatusOK, keys)
+}
+
+// handlerRevokeAPIKey revokes one of the authenticated user's API keys.
+// Requests made with it fail from then on.
+func (cfg *apiConfig) handlerRevokeAPIKey(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	keyID, err := uuid.Parse(r.PathValue("keyID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid keyID format")
+		return
+	}
+
+	revoked, err := cfg.DB.RevokeAPIKey(r.Context(), database.RevokeAPIKeyParams{
+		ID:     keyID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to revoke API key"))
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, ErrCodeNotFound, "API key not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
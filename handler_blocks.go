@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+// checkCanInteract returns a 403 error if either user has blocked the other.
+func (cfg *apiConfig) checkCanInteract(ctx context.Context, userID, otherID uuid.UUID) *APIError {
+	if userID == otherID {
+		return nil
+	}
+
+	blocked, err := cfg.DB.HasBlockBetween(ctx, database.HasBlockBetweenParams{
+		UserID:  userID,
+		OtherID: otherID,
+	})
+	if err != nil {
+		return dbError(err, "Failed to check blocks")
+	}
+	if blocked {
+		return newAPIError(http.StatusForbidden, ErrCodeForbidden, "You can't interact with this user")
+	}
+	return nil
+}
+
+// handlerBlockUser makes the authenticated user block the user with the
+// given handle. Follows between the two users are removed in both
+// directions. Blocking someone twice is not an error.
+func (cfg *apiConfig) handlerBlockUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	blockedID, apiErr := cfg.userIDByHandle(r.Context(), r.PathValue("handle"))
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	if blockedID == userID {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "You can't block yourself")
+		return
+	}
+
+	err := cfg.withTx(r.Context(), func(q *database.Queries) error {
+		err := q.BlockUser(r.Context(), database.BlockUserParams{
+			BlockerID: userID,
+			BlockedID: blockedID,
+		})
+		if err != nil {
+			return err
+		}
+		return q.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{
+			UserID:  userID,
+			OtherID: blockedID,
+		})
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to block user"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerUnblockUser removes the authenticated user's block on the user
+// with the given handle. Follows removed by the block are not restored.
+func (cfg *apiConfig) handlerUnblockUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	blockedID, apiErr := cfg.userIDByHandle(r.Context(), r.PathValue("handle"))
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	err := cfg.DB.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to unblock user"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerMuteUser hides the chirps of the user with the given handle from
+// the authenticated user. Unlike a block, the muted user is not told and
+// can still interact with the muter. Muting someone twice is not an error.
+func (cfg *apiConfig) handlerMuteUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	mutedID, apiErr := cfg.userIDByHandle(r.Context(), r.PathValue("handle"))
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	if mutedID == userID {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "You can't mute yourself")
+		return
+	}
+
+	err := cfg.DB.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to mute user"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerUnmuteUser removes the authenticated user's mute on the user with
+// the given handle.
+func (cfg *apiConfig) handlerUnmuteUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	mutedID, apiErr := cfg.userIDByHandle(r.Context(), r.PathValue("handle"))
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	err := cfg.DB.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to unmute user"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerGetBlocks lists the users the authenticated user has blocked,
+// most recent blocks first.
+func (cfg *apiConfig) handlerGetBlocks(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	cursor, limit, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	cursor = cursor.orLatest()
+
+	rows, err := cfg.DB.GetBlockedUsers(r.Context(), database.GetBlockedUsersParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		Limit:           limit,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get blocked users"))
+		return
+	}
+
+	page := ProfilePage{Users: make([]PublicProfile, len(rows))}
+	for i, row := range rows {
+		page.Users[i] = PublicProfile{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			Handle:      row.Handle.String,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			AvatarURL:   row.AvatarUrl,
+		}
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		page.NextCursor = pageCursor{CreatedAt: last.BlockedAt, ID: last.ID}.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// handlerGetMutes lists the users the authenticated user has muted,
+// most recent mutes first.
+func (cfg *apiConfig) handlerGetMutes(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	cursor, limit, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	cursor = cursor.orLatest()
+
+	rows, err := cfg.DB.GetMutedUsers(r.Context(), database.GetMutedUsersParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		Limit:           limit,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get muted users"))
+		return
+	}
+
+	page := ProfilePage{Users: make([]PublicProfile, len(rows))}
+	for i, row := range rows {
+		page.Users[i] = PublicProfile{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			Handle:      row.Handle.String,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			AvatarURL:   row.AvatarUrl,
+		}
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		page.NextCursor = pageCursor{CreatedAt: last.MutedAt, ID: last.ID}.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
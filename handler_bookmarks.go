@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerBookmarkChirp saves a chirp to the authenticated user's bookmarks.
+// Bookmarking a chirp twice is not an error.
+func (cfg *apiConfig) handlerBookmarkChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
+		return
+	}
+
+	chirp, err := cfg.DB.GetChirp(r.Context(), database.GetChirpParams{ID: chirpID, ViewerID: userID})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp"))
+		return
+	}
+	if !chirpVisibleTo(chirp, userID) {
+		respondWithError(w, http.StatusNotFound, ErrCodeNotFound, "Chirp not found")
+		return
+	}
+
+	err = cfg.DB.BookmarkChirp(r.Context(), database.BookmarkChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to bookmark chirp"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerUnbookmarkChirp removes a chirp from the authenticated user's
+// bookmarks. Removing a bookmark that does not exist is not an error.
+func (cfg *apiConfig) handlerUnbookmarkChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
+		return
+	}
+
+	err = cfg.DB.UnbookmarkChirp(r.Context(), database.UnbookmarkChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to remove bookmark"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerGetBookmarks returns the authenticated user's bookmarked chirps,
+// most recently bookmarked first, one page at a time.
+func (cfg *apiConfig) handlerGetBookmarks(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	cursor, limit, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	cursor = cursor.orLatest()
+
+	rows, err := cfg.DB.GetBookmarkedChirps(r.Context(), database.GetBookmarkedChirpsParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		Limit:           limit,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get bookmarks"))
+		return
+	}
+
+	chirps := make([]Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = chirpFromDB(database.Chirp{
+			ID:                row.ID,
+			CreatedAt:         row.CreatedAt,
+			UpdatedAt:         row.UpdatedAt,
+			Body:              row.Body,
+			UserID:            row.UserID,
+			ParentID:          row.ParentID,
+			Kind:              row.Kind,
+			ReferencedChirpID: row.ReferencedChirpID,
+			HiddenAt:          row.HiddenAt,
+			Status:            row.Status,
+			PublishAt:         row.PublishAt,
+		})
+	}
+
+	if err := cfg.hydrateChirps(r.Context(), chirps, userID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp details"))
+		return
+	}
+
+	page := Page{Chirps: chirps}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		page.NextCursor = pageCursor{CreatedAt: last.BookmarkedAt, ID: last.ID}.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
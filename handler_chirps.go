@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/chirptext"
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+// defaultMaxChirpLength is the maximum number of characters in a chirp
+// body unless CHIRP_MAX_LENGTH says otherwise.
+const defaultMaxChirpLength = 140
+
+// Kinds of chirps, stored in the chirps.kind column.
+const (
+	chirpKindChirp   = "chirp"
+	chirpKindRechirp = "rechirp"
+	chirpKindQuote   = "quote"
+)
+
+// Chirp statuses, stored in the chirps.status column.
+const (
+	chirpStatusScheduled = "scheduled"
+	chirpStatusPublished = "published"
+)
+
+// ChirpRevision is a previous version of an edited chirp.
+type ChirpRevision struct {
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// chirpFromDB converts a database chirp into its API representation.
+func chirpFromDB(dbChirp database.Chirp) Chirp {
+	return Chirp{
+		ID:        dbChirp.ID,
+		CreatedAt: dbChirp.CreatedAt,
+		UpdatedAt: dbChirp.UpdatedAt,
+		Body:      dbChirp.Body,
+		UserID:    dbChirp.UserID,
+		InReplyTo: nullUUIDPtr(dbChirp.ParentID),
+		Hidden:    dbChirp.HiddenAt.Valid,
+
+		Kind:              dbChirp.Kind,
+		ReferencedChirpID: nullUUIDPtr(dbChirp.ReferencedChirpID),
+		PublishAt:         scheduledPublishAt(dbChirp),
+	}
+}
+
+// scheduledPublishAt returns when a scheduled chirp will be published, or
+// nil once it has been.
+func scheduledPublishAt(dbChirp database.Chirp) *time.Time {
+	if dbChirp.Status != chirpStatusScheduled || !dbChirp.PublishAt.Valid {
+		return nil
+	}
+	return &dbChirp.PublishAt.Time
+}
+
+// chirpVisibleTo reports whether viewerID may see a chirp. Chirps hidden
+// by a moderator and chirps that are still scheduled are only visible to
+// their author.
+func chirpVisibleTo(dbChirp database.Chirp, viewerID uuid.UUID) bool {
+	if dbChirp.UserID == viewerID {
+		return true
+	}
+	return !dbChirp.HiddenAt.Valid && dbChirp.Status == chirpStatusPublished
+}
+
+// hydrateChirps fills in the parts of chirps that come from other tables:
+// the embedded referenced chirp of rechirps and quotes, like stats and
+// attached media.
+// Each part is loaded with one query for the whole slice.
+func (cfg *apiConfig) hydrateChirps(ctx context.Context, chirps []Chirp, viewerID uuid.UUID) error {
+	if err := cfg.addReferencedChirps(ctx, chirps, viewerID); err != nil {
+		return err
+	}
+	if err := cfg.addLikeStats(ctx, chirps, viewerID); err != nil {
+		return err
+	}
+	return cfg.addMedia(ctx, chirps)
+}
+
+// addReferencedChirps embeds the chirp each rechirp or quote refers to,
+// unless it is hidden from viewerID.
+func (cfg *apiConfig) addReferencedChirps(ctx context.Context, chirps []Chirp, viewerID uuid.UUID) error {
+	var ids []uuid.UUID
+	for _, chirp := range chirps {
+		if chirp.ReferencedChirpID != nil {
+			ids = append(ids, *chirp.ReferencedChirpID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	dbChirps, err := cfg.DB.GetChirpsByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[uuid.UUID]database.Chirp, len(dbChirps))
+	for _, dbChirp := range dbChirps {
+		if chirpVisibleTo(dbChirp, viewerID) {
+			byID[dbChirp.ID] = dbChirp
+		}
+	}
+
+	for i := range chirps {
+		if chirps[i].ReferencedChirpID == nil {
+			continue
+		}
+		if dbChirp, ok := byID[*chirps[i].ReferencedChirpID]; ok {
+			referenced := chirpFromDB(dbChirp)
+			chirps[i].ReferencedChirp = &referenced
+		}
+	}
+	return nil
+}
+
+// resolveChirpReference checks that the chirp being rechirped or quoted
+// exists and that its author has no block with userID. Rechirps are
+// resolved to the chirp they repost so that references always point at
+// content with a body.
+func (cfg *apiConfig) resolveChirpReference(ctx context.Context, userID, chirpID uuid.UUID) (uuid.UUID, *APIError) {
+	referenced, err := cfg.DB.GetChirp(ctx, database.GetChirpParams{ID: chirpID, ViewerID: userID})
+	if err != nil {
+		return uuid.Nil, dbError(err, "Referenced chirp not found")
+	}
+
+	if referenced.Status != chirpStatusPublished {
+		return uuid.Nil, newAPIError(http.StatusNotFound, ErrCodeNotFound, "Referenced chirp not found")
+	}
+
+	if referenced.Kind == chirpKindRechirp && referenced.ReferencedChirpID.Valid {
+		referenced, err = cfg.DB.GetChirp(ctx, database.GetChirpParams{
+			ID:       referenced.ReferencedChirpID.UUID,
+			ViewerID: userID,
+		})
+		if err != nil {
+			return uuid.Nil, dbError(err, "Referenced chirp not found")
+		}
+	}
+
+	if apiErr := cfg.checkCanInteract(ctx, userID, referenced.UserID); apiErr != nil {
+		return uuid.Nil, apiErr
+	}
+	return referenced.ID, nil
+}
+
+// nullUUIDPtr converts a nullable UUID into a pointer that is nil for NULL.
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+// cleanChirpBody normalizes a chirp body to NFC, checks that it is neither
+// empty nor too long and masks banned words with replacer. Length is
+// counted in user-perceived characters, not bytes. It is used for both
+// new and edited chirps.
+func (cfg *apiConfig) cleanChirpBody(body string) (string, *APIError) {
+	body = chirptext.Normalize(body)
+	if body == "" {
+		return "", newAPIError(http.StatusBadRequest, ErrCodeValidation, "Chirp is empty")
+	}
+	if length := chirptext.Length(body); length > cfg.maxChirpLength {
+		return "", &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "Chirp is too long",
+			Details: map[string]any{"length": length, "limit": cfg.maxChirpLength},
+		}
+	}
+	return replacer(body), nil
+}
+
+// handlerUpdateChirp replaces the body of a chirp owned by the authenticated user.
+// The previous body is kept in the chirp's edit history.
+func (cfg *apiConfig) handlerUpdateChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
+		return
+	}
+
+	cleanedBody, apiErr := cfg.cleanChirpBody(params.Body)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	existing, err := cfg.DB.GetChirp(r.Context(), database.GetChirpParams{ID: chirpID, ViewerID: userID})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp"))
+		return
+	}
+
+	if existing.UserID != userID {
+		respondWithError(w, http.StatusForbidden, ErrCodeForbidden, "You can't edit this chirp")
+		return
+	}
+
+	if existing.Kind == chirpKindRechirp {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Rechirps can't be edited")
+		return
+	}
+
+	var chirp database.Chirp
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		chirp, err = q.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+			ID:     chirpID,
+			UserID: userID,
+			Body:   cleanedBody,
+		})
+		if err != nil {
+			return err
+		}
+		if chirp.Status != chirpStatusPublished {
+			return nil
+		}
+		if err := q.DeleteChirpHashtags(r.Context(), chirp.ID); err != nil {
+			return err
+		}
+		if err := q.DeleteChirpMentions(r.Context(), chirp.ID); err != nil {
+			return err
+		}
+		return indexChirpText(r.Context(), q, chirp.ID, chirp.Body)
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to update chirp"))
+		return
+	}
+
+	chirps := []Chirp{chirpFromDB(chirp)}
+	if err := cfg.hydrateChirps(r.Context(), chirps, userID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to load updated chirp"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps[0])
+}
+
+// handlerGetChirpHistory returns the current version of a chirp together
+// with all of its previous versions, newest first.
+func (cfg *apiConfig) handlerGetChirpHistory(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
+		return
+	}
+
+	viewerID := cfg.optionalUserID(r)
+	dbChirp, err := cfg.DB.GetChirp(r.Context(), database.GetChirpParams{ID: chirpID, ViewerID: viewerID})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp"))
+		return
+	}
+
+	if !chirpVisibleTo(dbChirp, viewerID) {
+		respondWithError(w, http.StatusNotFound, ErrCodeNotFound, "Chirp not found")
+		return
+	}
+
+	dbRevisions, err := cfg.DB.GetChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp history"))
+		return
+	}
+
+	revisions := make([]ChirpRevision, len(dbRevisions))
+	for i, dbRevision := range dbRevisions {
+		revisions[i] = ChirpRevision{
+			Body:       dbRevision.Body,
+			CreatedAt:  dbRevision.CreatedAt,
+			ReplacedAt: dbRevision.ReplacedAt,
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, struct {
+		Current   Chirp           `json:"current"`
+		Revisions []ChirpRevision `json:"revisions"`
+	}{
+		Current:   chirpFromDB(dbChirp),
+		Revisions: revisions,
+	})
+}
+
+// handlerGetChirpReplies returns the direct replies to a chirp, oldest
+// first, one page at a time.
+func (cfg *apiConfig) handlerGetChirpReplies(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
+		return
+	}
+
+	cursor, limit, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	viewerID := cfg.optionalUserID(r)
+	dbChirps, err := cfg.DB.GetChirpReplies(r.Context(), database.GetChirpRepliesParams{
+		ParentID:       uuid.NullUUID{UUID: chirpID, Valid: true},
+		AfterCreatedAt: cursor.CreatedAt,
+		AfterID:        cursor.ID,
+		ViewerID:       viewerID,
+		Limit:          limit,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get replies"))
+		return
+	}
+
+	chirps := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirps[i] = chirpFromDB(dbChirp)
+	}
+
+	if err := cfg.hydrateChirps(r.Context(), chirps, viewerID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp details"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newPage(chirps, limit))
+}
+
+// ThreadChirp is a chirp within a thread. Depth is the number of reply
+// levels below the requested chirp.
+type ThreadChirp struct {
+	Chirp
+	Depth int32 `json:"depth"`
+}
+
+// handlerGetChirpThread returns the conversation a chirp belongs to:
+// the chain of chirps it replies to, root first, the chirp itself and
+// every reply below it.
+func (cfg *apiConfig) handlerGetChirpThread(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
+		return
+	}
+
+	viewerID := cfg.optionalUserID(r)
+	allRows, err := cfg.DB.GetChirpThread(r.Context(), database.GetChirpThreadParams{
+		ID:       chirpID,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get thread"))
+		return
+	}
+
+	var rows []database.GetChirpThreadRow
+	var chirps []Chirp
+	for _, row := range allRows {
+		dbChirp := database.Chirp{
+			ID:                row.ID,
+			CreatedAt:         row.CreatedAt,
+			UpdatedAt:         row.UpdatedAt,
+			Body:              row.Body,
+			UserID:            row.UserID,
+			ParentID:          row.ParentID,
+			Kind:              row.Kind,
+			ReferencedChirpID: row.ReferencedChirpID,
+			HiddenAt:          row.HiddenAt,
+			Status:            row.Status,
+			PublishAt:         row.PublishAt,
+		}
+		if !chirpVisibleTo(dbChirp, viewerID) {
+			continue
+		}
+		rows = append(rows, row)
+		chirps = append(chirps, chirpFromDB(dbChirp))
+	}
+
+	if err := cfg.hydrateChirps(r.Context(), chirps, viewerID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp details"))
+		return
+	}
+
+	var focus *Chirp
+	ancestors := []Chirp{}
+	replies := []ThreadChirp{}
+	for i, row := range rows {
+		switch {
+		case row.Depth < 0:
+			ancestors = append(ancestors, chirps[i])
+		case row.Depth == 0:
+			focus = &chirps[i]
+		default:
+			replies = append(replies, ThreadChirp{Chirp: chirps[i], Depth: row.Depth})
+		}
+	}
+
+	if focus == nil {
+		respondWithError(w, http.StatusNotFound, ErrCodeNotFound, "Chirp not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, struct {
+		Ancestors []Chirp       `json:"ancestors"`
+		Chirp     Chirp         `json:"chirp"`
+		Replies   []ThreadChirp `json:"replies"`
+	}{
+		Ancestors: ancestors,
+		Chirp:     *focus,
+		Replies:   replies,
+	})
+}
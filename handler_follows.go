@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+// userIDByHandle resolves the handle in the request path to a user ID.
+func (cfg *apiConfig) userIDByHandle(ctx context.Context, rawHandle string) (uuid.UUID, *APIError) {
+	handle, ok := normalizeHandle(rawHandle)
+	if !ok {
+		return uuid.Nil, newAPIError(http.StatusNotFound, ErrCodeNotFound, "User not found")
+	}
+
+	profile, err := cfg.DB.GetPublicProfileByHandle(ctx, sql.NullString{String: handle, Valid: true})
+	if err != nil {
+		return uuid.Nil, dbError(err, "User not found")
+	}
+	return profile.ID, nil
+}
+
+// handlerFollowUser makes the authenticated user follow the user with the
+// given handle. Following someone twice is not an error.
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	followeeID, apiErr := cfg.userIDByHandle(r.Context(), r.PathValue("handle"))
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	if followeeID == userID {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "You can't follow yourself")
+		return
+	}
+
+	if apiErr := cfg.checkCanInteract(r.Context(), userID, followeeID); apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	err := cfg.DB.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to follow user"))
+		return
+	}
+
+	cfg.notify(r.Context(), database.CreateNotificationParams{
+		UserID:  followeeID,
+		ActorID: userID,
+		Type:    notificationFollow,
+	})
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerUnfollowUser makes the authenticated user stop following the user
+// with the given handle.
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	followeeID, apiErr := cfg.userIDByHandle(r.Context(), r.PathValue("handle"))
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	err := cfg.DB.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to unfollow user"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerGetFollowers lists the users following the user with the given
+// handle, most recent follows first.
+func (cfg *apiConfig) handlerGetFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, apiErr := cfg.userIDByHandle(r.Context(), r.PathValue("handle"))
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	cursor, limit, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	cursor = cursor.orLatest()
+
+	rows, err := cfg.DB.GetFollowers(r.Context(), database.GetFollowersParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		Limit:           limit,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get followers"))
+		return
+	}
+
+	page := ProfilePage{Users: make([]PublicProfile, len(rows))}
+	for i, row := range rows {
+		page.Users[i] = PublicProfile{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			Handle:      row.Handle.String,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			AvatarURL:   row.AvatarUrl,
+		}
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		page.NextCursor = pageCursor{CreatedAt: last.FollowedAt, ID: last.ID}.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// handlerGetFollowing lists the users that the user with the given handle
+// follows, most recent follows first.
+func (cfg *apiConfig) handlerGetFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, apiErr := cfg.userIDByHandle(r.Context(), r.PathValue("handle"))
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	cursor, limit, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	cursor = cursor.orLatest()
+
+	rows, err := cfg.DB.GetFollowing(r.Context(), database.GetFollowingParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		Limit:           limit,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get followed users"))
+		return
+	}
+
+	page := ProfilePage{Users: make([]PublicProfile, len(rows))}
+	for i, row := range rows {
+		page.Users[i] = PublicProfile{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			Handle:      row.Handle.String,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			AvatarURL:   row.AvatarUrl,
+		}
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		page.NextCursor = pageCursor{CreatedAt: last.FollowedAt, ID: last.ID}.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// handlerGetTimeline returns the chirps of the accounts the authenticated
+// user follows, newest first, one page at a time.
+func (cfg *apiConfig) handlerGetTimeline(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	cursor, limit, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	cursor = cursor.orLatest()
+
+	dbChirps, err := cfg.DB.GetHomeTimeline(r.Context(), database.GetHomeTimelineParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		Limit:           limit,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get timeline"))
+		return
+	}
+
+	chirps := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirps[i] = chirpFromDB(dbChirp)
+	}
+
+	if err := cfg.hydrateChirps(r.Context(), chirps, userID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp details"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newPage(chirps, limit))
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/chirptext"
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	defaultTrendingLimit  = 10
+)
+
+// indexChirpText stores the hashtags and mentions found in a chirp body.
+// Mentions of handles that don't belong to anyone are ignored.
+func indexChirpText(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+	if tags := chirptext.Hashtags(body); len(tags) > 0 {
+		err := q.AddChirpHashtags(ctx, database.AddChirpHashtagsParams{
+			ChirpID: chirpID,
+			Tags:    tags,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if handles := chirptext.Mentions(body); len(handles) > 0 {
+		err := q.AddChirpMentions(ctx, database.AddChirpMentionsParams{
+			ChirpID: chirpID,
+			Handles: handles,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handlerGetHashtagChirps returns the chirps tagged with a hashtag,
+// newest first, one page at a time.
+func (cfg *apiConfig) handlerGetHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if tag == "" {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid hashtag")
+		return
+	}
+
+	cursor, limit, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	cursor = cursor.orLatest()
+
+	viewerID := cfg.optionalUserID(r)
+	dbChirps, err := cfg.DB.GetChirpsByHashtag(r.Context(), database.GetChirpsByHashtagParams{
+		Tag:             tag,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		ViewerID:        viewerID,
+		Limit:           limit,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirps"))
+		return
+	}
+
+	chirps := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirps[i] = chirpFromDB(dbChirp)
+	}
+
+	if err := cfg.hydrateChirps(r.Context(), chirps, viewerID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp details"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newPage(chirps, limit))
+}
+
+// handlerGetMentions returns the chirps that mention the authenticated
+// user, newest first, one page at a time.
+func (cfg *apiConfig) handlerGetMentions(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	cursor, limit, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	cursor = cursor.orLatest()
+
+	dbChirps, err := cfg.DB.GetMentionsOfUser(r.Context(), database.GetMentionsOfUserParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		Limit:           limit,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get mentions"))
+		return
+	}
+
+	chirps := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirps[i] = chirpFromDB(dbChirp)
+	}
+
+	if err := cfg.hydrateChirps(r.Context(), chirps, userID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp details"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newPage(chirps, limit))
+}
+
+// handlerGetTrendingHashtags returns the hashtags used by the most chirps
+// within a sliding window ending now. The window defaults to 24 hours and
+// can be set with the "window" query parameter, e.g. ?window=6h.
+func (cfg *apiConfig) handlerGetTrendingHashtags(w http.ResponseWriter, r *http.Request) {
+	window := defaultTrendingWindow
+	if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+		parsed, err := time.ParseDuration(windowStr)
+		if err != nil || parsed <= 0 || parsed > maxTrendingWindow {
+			respondWithAPIError(w, &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    ErrCodeValidation,
+				Message: "Invalid window",
+				Details: map[string]any{"max": maxTrendingWindow.String()},
+			})
+			return
+		}
+		window = parsed
+	}
+
+	limit := defaultTrendingLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 1 || n > maxPageSize {
+			respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	rows, err := cfg.DB.GetTrendingHashtags(r.Context(), database.GetTrendingHashtagsParams{
+		Since: time.Now().UTC().Add(-window),
+		Limit: int32(limit),
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get trending hashtags"))
+		return
+	}
+
+	type trendingHashtag struct {
+		Tag        string `json:"tag"`
+		ChirpCount int64  `json:"chirp_count"`
+	}
+
+	hashtags := make([]trendingHashtag, len(rows))
+	for i, row := range rows {
+		hashtags[i] = trendingHashtag{Tag: row.Tag, ChirpCount: row.ChirpCount}
+	}
+
+	respondWithJSON(w, http.StatusOK, struct {
+		Window   string            `json:"window"`
+		Hashtags []trendingHashtag `json:"hashtags"`
+	}{
+		Window:   window.String(),
+		Hashtags: hashtags,
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+// handlerLikeChirp likes a chirp on behalf of the authenticated user.
+// Liking a chirp twice is not an error.
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
+		return
+	}
+
+	chirp, err := cfg.DB.GetChirp(r.Context(), database.GetChirpParams{ID: chirpID, ViewerID: userID})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp"))
+		return
+	}
+	if !chirpVisibleTo(chirp, userID) {
+		respondWithError(w, http.StatusNotFound, ErrCodeNotFound, "Chirp not found")
+		return
+	}
+
+	if apiErr := cfg.checkCanInteract(r.Context(), userID, chirp.UserID); apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	err = cfg.DB.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to like chirp"))
+		return
+	}
+
+	cfg.notify(r.Context(), database.CreateNotificationParams{
+		UserID:  chirp.UserID,
+		ActorID: userID,
+		Type:    notificationLike,
+		ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+	})
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerUnlikeChirp removes the authenticated user's like from a chirp.
+// Removing a like that does not exist is not an error.
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
+		return
+	}
+
+	err = cfg.DB.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to unlike chirp"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// addLikeStats fills in LikeCount and LikedByMe for chirps with a single
+// query. viewerID is uuid.Nil for anonymous requests.
+func (cfg *apiConfig) addLikeStats(ctx context.Context, chirps []Chirp, viewerID uuid.UUID) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+
+	stats, err := cfg.DB.GetChirpLikeStats(ctx, database.GetChirpLikeStatsParams{
+		ViewerID: viewerID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	byChirp := make(map[uuid.UUID]database.GetChirpLikeStatsRow, len(stats))
+	for _, stat := range stats {
+		byChirp[stat.ChirpID] = stat
+	}
+
+	for i := range chirps {
+		stat := byChirp[chirps[i].ID]
+		chirps[i].LikeCount = stat.LikeCount
+		chirps[i].LikedByMe = stat.LikedByMe
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/blobstore"
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/BabichevDima/goServer/internal/media"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultMediaDir is where uploads are stored unless MEDIA_DIR says
+	// otherwise.
+	defaultMediaDir = "./media"
+	// maxMediaSize is the largest image file that can be uploaded.
+	maxMediaSize = 5 << 20
+	// maxChirpMedia is the number of images that can be attached to a chirp.
+	maxChirpMedia = 4
+
+	mediaURLPrefix = "/assets/media/"
+	// Blob keys are random and never reused, so clients may cache media
+	// forever.
+	mediaCacheControl = "public, max-age=31536000, immutable"
+)
+
+// errMediaNotAttachable is returned inside the chirp creation transaction
+// when some of the requested media can't be attached to the new chirp.
+var errMediaNotAttachable = errors.New("media not attachable")
+
+// MediaAttachment is an uploaded image as shown to API clients.
+type MediaAttachment struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+}
+
+// mediaFromDB converts a database media row into its API representation.
+func mediaFromDB(dbMedia database.Media) MediaAttachment {
+	return MediaAttachment{
+		ID:           dbMedia.ID,
+		URL:          mediaURLPrefix + dbMedia.StorageKey,
+		ThumbnailURL: mediaURLPrefix + dbMedia.ThumbnailKey,
+		ContentType:  dbMedia.ContentType,
+		Width:        dbMedia.Width,
+		Height:       dbMedia.Height,
+	}
+}
+
+// handlerUploadMedia accepts a single image in the "file" field of a
+// multipart form. The image type is detected from its contents, its
+// metadata is stripped and a thumbnail is generated. The returned ID can
+// be passed in "media_ids" when creating a chirp.
+func (cfg *apiConfig) handlerUploadMedia(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	// Leave room for the multipart headers around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, maxMediaSize+64<<10)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithAPIError(w, mediaTooLargeError())
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Expected an image in the \"file\" form field")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxMediaSize+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Failed to read upload")
+		return
+	}
+	if len(data) > maxMediaSize {
+		respondWithAPIError(w, mediaTooLargeError())
+		return
+	}
+
+	img, err := media.Process(data)
+	switch {
+	case errors.Is(err, media.ErrUnsupportedType):
+		respondWithError(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMedia, "Only JPEG, PNG and GIF images are supported")
+		return
+	case errors.Is(err, media.ErrTooLarge):
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Image dimensions are too large")
+		return
+	case err != nil:
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid image")
+		return
+	}
+
+	name := uuid.NewString()
+	storageKey := name + img.Ext
+	thumbnailKey := name + "_thumb" + img.ThumbnailExt
+	if err := cfg.putBlob(r.Context(), storageKey, img.Data); err != nil {
+		respondWithAPIError(w, &APIError{Status: http.StatusInternalServerError, Code: ErrCodeInternal, Message: "Failed to store image", Err: err})
+		return
+	}
+	if err := cfg.putBlob(r.Context(), thumbnailKey, img.Thumbnail); err != nil {
+		cfg.deleteBlobs(r.Context(), storageKey)
+		respondWithAPIError(w, &APIError{Status: http.StatusInternalServerError, Code: ErrCodeInternal, Message: "Failed to store image", Err: err})
+		return
+	}
+
+	dbMedia, err := cfg.DB.CreateMedia(r.Context(), database.CreateMediaParams{
+		UserID:       userID,
+		ContentType:  img.ContentType,
+		Width:        int32(img.Width),
+		Height:       int32(img.Height),
+		ByteSize:     int32(len(img.Data)),
+		StorageKey:   storageKey,
+		ThumbnailKey: thumbnailKey,
+	})
+	if err != nil {
+		cfg.deleteBlobs(r.Context(), storageKey, thumbnailKey)
+		respondWithAPIError(w, dbError(err, "Failed to save image"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, mediaFromDB(dbMedia))
+}
+
+func mediaTooLargeError() *APIError {
+	return &APIError{
+		Status:  http.StatusRequestEntityTooLarge,
+		Code:    ErrCodePayloadTooLarge,
+		Message: "Image is too large",
+		Details: map[string]any{"limit_bytes": maxMediaSize},
+	}
+}
+
+// handlerServeMedia serves an uploaded image or thumbnail from the blob store.
+func (cfg *apiConfig) handlerServeMedia(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	blob, err := cfg.blobs.Open(r.Context(), key)
+	if errors.Is(err, blobstore.ErrNotFound) || errors.Is(err, blobstore.ErrInvalidKey) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to open media %q: %v", key, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	// Only images are ever stored, but never let a browser guess otherwise.
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(key)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", mediaCacheControl)
+
+	if seeker, ok := blob.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, key, time.Time{}, seeker)
+		return
+	}
+	io.Copy(w, blob)
+}
+
+// addMedia attaches the uploaded images of chirps and of the chirps they
+// embed, loaded with one query.
+func (cfg *apiConfig) addMedia(ctx context.Context, chirps []Chirp) error {
+	var targets []*Chirp
+	for i := range chirps {
+		targets = append(targets, &chirps[i])
+		if chirps[i].ReferencedChirp != nil {
+			targets = append(targets, chirps[i].ReferencedChirp)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(targets))
+	for i, chirp := range targets {
+		ids[i] = chirp.ID
+	}
+
+	dbMedia, err := cfg.DB.GetMediaForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	byChirp := make(map[uuid.UUID][]MediaAttachment)
+	for _, m := range dbMedia {
+		byChirp[m.ChirpID.UUID] = append(byChirp[m.ChirpID.UUID], mediaFromDB(m))
+	}
+
+	for _, chirp := range targets {
+		chirp.Media = byChirp[chirp.ID]
+	}
+	return nil
+}
+
+func (cfg *apiConfig) putBlob(ctx context.Context, key string, data []byte) error {
+	return cfg.blobs.Put(ctx, key, bytes.NewReader(data))
+}
+
+// deleteBlobs removes blobs whose database rows are gone or were never
+// created. Failures only leave unreferenced files behind, so they are
+// logged rather than reported to the client.
+func (cfg *apiConfig) deleteBlobs(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := cfg.blobs.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete media %q: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+// Roles, stored in the users.role column.
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+// Report statuses, stored in the reports.status column.
+const (
+	reportStatusOpen      = "open"
+	reportStatusResolved  = "resolved"
+	reportStatusDismissed = "dismissed"
+)
+
+const maxReportDetailsLength = 500
+
+// reportReasons are the reason codes a chirp can be reported for.
+// They must match the valid_reason constraint on the reports table.
+var reportReasons = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"self_harm":      true,
+	"misinformation": true,
+	"other":          true,
+}
+
+// Report is a user's report of a chirp. Chirp is only filled in for the
+// moderation queue.
+type Report struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ChirpID    uuid.UUID  `json:"chirp_id"`
+	ReporterID uuid.UUID  `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details,omitempty"`
+	Status     string     `json:"status"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy *uuid.UUID `json:"resolved_by,omitempty"`
+	Chirp      *Chirp     `json:"chirp,omitempty"`
+}
+
+// reportFromDB converts a database report into its API representation.
+func reportFromDB(dbReport database.Report) Report {
+	report := Report{
+		ID:         dbReport.ID,
+		CreatedAt:  dbReport.CreatedAt,
+		ChirpID:    dbReport.ChirpID,
+		ReporterID: dbReport.ReporterID,
+		Reason:     dbReport.Reason,
+		Details:    dbReport.Details,
+		Status:     dbReport.Status,
+		ResolvedBy: nullUUIDPtr(dbReport.ResolvedBy),
+	}
+	if dbReport.ResolvedAt.Valid {
+		report.ResolvedAt = &dbReport.ResolvedAt.Time
+	}
+	return report
+}
+
+// middlewareModerator works like middlewareAuth but also requires the
+// user to be a moderator or an admin. It responds with 403 otherwise.
+func (cfg *apiConfig) middlewareModerator(next authedHandler) http.Handler {
+	return cfg.middlewareRole(next, "Moderator access required", roleModerator, roleAdmin)
+}
+
+// middlewareAdmin works like middlewareAuth but also requires the user to
+// be an admin. It responds with 403 otherwise.
+func (cfg *apiConfig) middlewareAdmin(next authedHandler) http.Handler {
+	return cfg.middlewareRole(next, "Admin access required", roleAdmin)
+}
+
+// middlewareRole requires the authenticated user to have one of roles and
+// responds with 403 and message otherwise. Moderation isn't available to
+// API keys.
+func (cfg *apiConfig) middlewareRole(next authedHandler, message string, roles ...string) http.Handler {
+	return cfg.middlewareAuthScope(scopeSession, func(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+		user, err := cfg.DB.GetUserByID(r.Context(), userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				respondWithError(w, http.StatusUnauthorized, ErrCodeInvalidToken, "Invalid access token")
+				return
+			}
+			respondWithAPIError(w, dbError(err, "Failed to get user data"))
+			return
+		}
+
+		if !slices.Contains(roles, user.Role) {
+			respondWithError(w, http.StatusForbidden, ErrCodeForbidden, message)
+			return
+		}
+
+		next(w, r, userID)
+	})
+}
+
+// handlerReportChirp files a report about a chirp for moderators to review.
+// A user can have only one open report per chirp.
+func (cfg *apiConfig) handlerReportChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	type parameters struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
+		return
+	}
+
+	if !reportReasons[params.Reason] {
+		respondWithAPIError(w, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "Invalid report reason",
+			Details: map[string]any{"reasons": slices.Sorted(maps.Keys(reportReasons))},
+		})
+		return
+	}
+
+	details := strings.TrimSpace(params.Details)
+	if utf8.RuneCountInString(details) > maxReportDetailsLength {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Report details are too long")
+		return
+	}
+
+	chirp, err := cfg.DB.GetChirp(r.Context(), database.GetChirpParams{ID: chirpID, ViewerID: userID})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp"))
+		return
+	}
+	if !chirpVisibleTo(chirp, userID) {
+		respondWithError(w, http.StatusNotFound, ErrCodeNotFound, "Chirp not found")
+		return
+	}
+
+	if chirp.UserID == userID {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "You can't report your own chirp")
+		return
+	}
+
+	report, err := cfg.DB.CreateReport(r.Context(), database.CreateReportParams{
+		ChirpID:    chirpID,
+		ReporterID: userID,
+		Reason:     params.Reason,
+		Details:    details,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			respondWithAPIError(w, dbError(err, "You already reported this chirp"))
+			return
+		}
+		respondWithAPIError(w, dbError(err, "Failed to report chirp"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, reportFromDB(report))
+}
+
+// handlerGetReports returns the moderation queue: reports with the status
+// given by the "status" query parameter (open by default), oldest first,
+// each with the reported chirp, hidden or not.
+func (cfg *apiConfig) handlerGetReports(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	status := reportStatusOpen
+	if statusStr := r.URL.Query().Get("status"); statusStr != "" {
+		if statusStr != reportStatusOpen && statusStr != reportStatusResolved && statusStr != reportStatusDismissed {
+			respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid status")
+			return
+		}
+		status = statusStr
+	}
+
+	cursor, limit, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	dbReports, err := cfg.DB.GetReports(r.Context(), database.GetReportsParams{
+		Status:         status,
+		AfterCreatedAt: cursor.CreatedAt,
+		AfterID:        cursor.ID,
+		Limit:          limit,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get reports"))
+		return
+	}
+
+	chirpIDs := make([]uuid.UUID, len(dbReports))
+	for i, dbReport := range dbReports {
+		chirpIDs[i] = dbReport.ChirpID
+	}
+
+	dbChirps, err := cfg.DB.GetChirpsByIDs(r.Context(), chirpIDs)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get reported chirps"))
+		return
+	}
+
+	chirpsByID := make(map[uuid.UUID]Chirp, len(dbChirps))
+	for _, dbChirp := range dbChirps {
+		chirpsByID[dbChirp.ID] = chirpFromDB(dbChirp)
+	}
+
+	reports := make([]Report, len(dbReports))
+	for i, dbReport := range dbReports {
+		reports[i] = reportFromDB(dbReport)
+		if chirp, ok := chirpsByID[dbReport.ChirpID]; ok {
+			reports[i].Chirp = &chirp
+		}
+	}
+
+	var nextCursor string
+	if len(dbReports) == int(limit) {
+		last := dbReports[len(dbReports)-1]
+		nextCursor = pageCursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, struct {
+		Reports    []Report `json:"reports"`
+		NextCursor string   `json:"next_cursor,omitempty"`
+	}{
+		Reports:    reports,
+		NextCursor: nextCursor,
+	})
+}
+
+// handlerResolveReport closes an open report as acted upon. If the body
+// sets "hide_chirp", the reported chirp is hidden in the same transaction.
+func (cfg *apiConfig) handlerResolveReport(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	type parameters struct {
+		HideChirp bool `json:"hide_chirp"`
+	}
+
+	params := parameters{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
+			return
+		}
+	}
+
+	cfg.closeReport(w, r, userID, reportStatusResolved, params.HideChirp)
+}
+
+// handlerDismissReport closes an open report without taking action.
+func (cfg *apiConfig) handlerDismissReport(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	cfg.closeReport(w, r, userID, reportStatusDismissed, false)
+}
+
+// closeReport sets the status of the open report in the request path and
+// optionally hides the reported chirp. Closed reports can't be reopened.
+func (cfg *apiConfig) closeReport(w http.ResponseWriter, r *http.Request, moderatorID uuid.UUID, status string, hideChirp bool) {
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid reportID format")
+		return
+	}
+
+	existing, err := cfg.DB.GetReport(r.Context(), reportID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Report not found"))
+		return
+	}
+	if existing.Status != reportStatusOpen {
+		respondWithError(w, http.StatusConflict, ErrCodeValidation, "Report is already closed")
+		return
+	}
+
+	var report database.Report
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		report, err = q.CloseReport(r.Context(), database.CloseReportParams{
+			Status:     status,
+			ResolvedBy: uuid.NullUUID{UUID: moderatorID, Valid: true},
+			ID:         reportID,
+		})
+		if err != nil {
+			return err
+		}
+		if hideChirp {
+			return q.HideChirp(r.Context(), report.ChirpID)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, ErrCodeValidation, "Report is already closed")
+			return
+		}
+		respondWithAPIError(w, dbError(err, "Failed to close report"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, reportFromDB(report))
+}
+
+// handlerHideChirp hides a chirp from everyone except its author.
+// Hiding a hidden chirp is not an error.
+func (cfg *apiConfig) handlerHideChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	cfg.setChirpHidden(w, r, true)
+}
+
+// handlerUnhideChirp makes a hidden chirp public again.
+func (cfg *apiConfig) handlerUnhideChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	cfg.setChirpHidden(w, r, false)
+}
+
+// setChirpHidden hides or unhides the chirp in the request path.
+func (cfg *apiConfig) setChirpHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
+		return
+	}
+
+	// Moderators act regardless of blocks, so no viewer is passed.
+	if _, err := cfg.DB.GetChirp(r.Context(), database.GetChirpParams{ID: chirpID}); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp"))
+		return
+	}
+
+	if hidden {
+		err = cfg.DB.HideChirp(r.Context(), chirpID)
+	} else {
+		err = cfg.DB.UnhideChirp(r.Context(), chirpID)
+	}
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to update chirp"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+// Kinds of notifications, stored in the notifications.type column.
+const (
+	notificationLike    = "like"
+	notificationReply   = "reply"
+	notificationMention = "mention"
+	notificationFollow  = "follow"
+)
+
+// maxMarkReadIDs is the number of notifications that can be marked read
+// by ID in one request.
+const maxMarkReadIDs = 100
+
+// Notification tells a user that someone interacted with them.
+type Notification struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Type        string     `json:"type"`
+	ActorID     uuid.UUID  `json:"actor_id"`
+	ActorHandle string     `json:"actor_handle"`
+	ChirpID     *uuid.UUID `json:"chirp_id,omitempty"`
+	Read        bool       `json:"read"`
+}
+
+// NotificationPage is a page of notifications with the cursor for the
+// next page and the number of unread notifications overall.
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int64          `json:"unread_count"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
+}
+
+// notify records a notification outside of a transaction. Notifications
+// are a side effect of the action that caused them, so failures are
+// logged rather than failing the action.
+func (cfg *apiConfig) notify(ctx context.Context, params database.CreateNotificationParams) {
+	if err := cfg.DB.CreateNotification(ctx, params); err != nil {
+		log.Printf("Failed to create %s notification for user %s: %v", params.Type, params.UserID, err)
+	}
+}
+
+// notifyChirpPublished notifies the author of the chirp being replied to
+// and the users mentioned in a newly published chirp. It must run after
+// the chirp's mentions are indexed.
+func notifyChirpPublished(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	if chirp.ParentID.Valid {
+		parent, err := q.GetChirp(ctx, database.GetChirpParams{
+			ID:       chirp.ParentID.UUID,
+			ViewerID: chirp.UserID,
+		})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil {
+			err = q.CreateNotification(ctx, database.CreateNotificationParams{
+				UserID:  parent.UserID,
+				ActorID: chirp.UserID,
+				Type:    notificationReply,
+				ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return q.CreateMentionNotifications(ctx, chirp.ID)
+}
+
+// handlerGetNotifications returns the authenticated user's notifications,
+// newest first, one page at a time.
+func (cfg *apiConfig) handlerGetNotifications(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	cursor, limit, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	cursor = cursor.orLatest()
+
+	rows, err := cfg.DB.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID:          userID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		Limit:           limit,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get notifications"))
+		return
+	}
+
+	unread, err := cfg.DB.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get notifications"))
+		return
+	}
+
+	page := NotificationPage{
+		Notifications: make([]Notification, len(rows)),
+		UnreadCount:   unread,
+	}
+	for i, row := range rows {
+		page.Notifications[i] = Notification{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			Type:        row.Type,
+			ActorID:     row.ActorID,
+			ActorHandle: row.ActorHandle.String,
+			ChirpID:     nullUUIDPtr(row.ChirpID),
+			Read:        row.ReadAt.Valid,
+		}
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		page.NextCursor = pageCursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// handlerMarkNotificationsRead marks the notifications listed in "ids" as
+// read, or all of the user's notifications if "ids" is empty or missing.
+// It returns the number of unread notifications left.
+func (cfg *apiConfig) handlerMarkNotificationsRead(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	type parameters struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+	type response struct {
+		UnreadCount int64 `json:"unread_count"`
+	}
+
+	params := parameters{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
+			return
+		}
+	}
+	if len(params.IDs) > maxMarkReadIDs {
+		respondWithAPIError(w, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "Too many notification IDs",
+			Details: map[string]any{"limit": maxMarkReadIDs},
+		})
+		return
+	}
+
+	var err error
+	if len(params.IDs) == 0 {
+		_, err = cfg.DB.MarkAllNotificationsRead(r.Context(), userID)
+	} else {
+		_, err = cfg.DB.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+			UserID: userID,
+			Ids:    params.IDs,
+		})
+	}
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to mark notifications read"))
+		return
+	}
+
+	unread, err := cfg.DB.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to count notifications"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{UnreadCount: unread})
+}
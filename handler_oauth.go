@@ -0,0 +1,600 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BabichevDima/goServer/internal/auth"
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxOAuthClientsPerUser   = 20
+	maxOAuthClientNameLength = 100
+	maxOAuthRedirectURIs     = 10
+	maxOAuthRedirectURILen   = 2000
+	// oauthCodeTTL is how long an authorization code can be exchanged for
+	// an access token.
+	oauthCodeTTL = 10 * time.Minute
+	// oauthAccessTokenTTL is how long access tokens issued to OAuth clients
+	// are valid. Clients get no refresh token and send the user through
+	// /oauth/authorize again once it expires.
+	oauthAccessTokenTTL = time.Hour
+	// maxOAuthFormSize limits the forms posted to the /oauth endpoints.
+	maxOAuthFormSize = 16 << 10
+)
+
+// OAuth error codes, as defined by RFC 6749. They are only used by the
+// /oauth endpoints, which third-party clients talk to with standard
+// libraries, so they don't follow the problem+json format of the API.
+const (
+	oauthErrInvalidRequest      = "invalid_request"
+	oauthErrInvalidClient       = "invalid_client"
+	oauthErrInvalidGrant        = "invalid_grant"
+	oauthErrInvalidScope        = "invalid_scope"
+	oauthErrAccessDenied        = "access_denied"
+	oauthErrUnsupportedGrant    = "unsupported_grant_type"
+	oauthErrUnsupportedResponse = "unsupported_response_type"
+	oauthErrServerError         = "server_error"
+)
+
+// scopeDescriptions are shown on the consent page.
+var scopeDescriptions = map[string]string{
+	scopeRead:         "Read your chirps, timeline and account",
+	scopeWrite:        "Like, bookmark, follow, block and mute on your behalf",
+	scopeChirpsWrite:  "Post and edit chirps on your behalf",
+	scopeChirpsDelete: "Delete your chirps",
+}
+
+// OAuthClient describes a registered third-party app. ClientSecret is only
+// returned when a confidential client is registered.
+type OAuthClient struct {
+	ClientID     uuid.UUID `json:"client_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+}
+
+func oauthClientFromDB(dbClient database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ClientID:     dbClient.ID,
+		CreatedAt:    dbClient.CreatedAt,
+		Name:         dbClient.Name,
+		RedirectURIs: dbClient.RedirectUris,
+		Confidential: dbClient.HashedSecret.Valid,
+	}
+}
+
+// validateRedirectURI checks that uri is an absolute https URL, or an http
+// URL on the loopback interface for apps under development, without a
+// fragment.
+func validateRedirectURI(uri string) bool {
+	if len(uri) > maxOAuthRedirectURILen {
+		return false
+	}
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" || u.Fragment != "" || u.User != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	}
+	return false
+}
+
+// handlerCreateOAuthClient registers a third-party app owned by the
+// authenticated user. Confidential clients get a secret, which is only
+// included in this response.
+func (cfg *apiConfig) handlerCreateOAuthClient(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	type parameters struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Confidential bool     `json:"confidential"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
+		return
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxOAuthClientNameLength {
+		respondWithAPIError(w, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "Client name is required and must be short",
+			Details: map[string]any{"max_length": maxOAuthClientNameLength},
+		})
+		return
+	}
+	if len(params.RedirectURIs) == 0 || len(params.RedirectURIs) > maxOAuthRedirectURIs {
+		respondWithAPIError(w, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "Between one and ten redirect URIs are required",
+			Details: map[string]any{"limit": maxOAuthRedirectURIs},
+		})
+		return
+	}
+	for _, uri := range params.RedirectURIs {
+		if !validateRedirectURI(uri) {
+			respondWithAPIError(w, &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    ErrCodeValidation,
+				Message: "Redirect URIs must be https URLs, or http URLs on localhost, without a fragment",
+				Details: map[string]any{"redirect_uri": uri},
+			})
+			return
+		}
+	}
+
+	clients, err := cfg.DB.GetOAuthClients(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to register client"))
+		return
+	}
+	if len(clients) >= maxOAuthClientsPerUser {
+		respondWithAPIError(w, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "Too many OAuth clients",
+			Details: map[string]any{"limit": maxOAuthClientsPerUser},
+		})
+		return
+	}
+
+	var secret string
+	var hashedSecret sql.NullString
+	if params.Confidential {
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			respondWithAPIError(w, &APIError{Status: http.StatusInternalServerError, Code: ErrCodeInternal, Message: "Failed to register client", Err: err})
+			return
+		}
+		hashedSecret = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	dbClient, err := cfg.DB.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		UserID:       userID,
+		Name:         name,
+		RedirectUris: params.RedirectURIs,
+		HashedSecret: hashedSecret,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to register client"))
+		return
+	}
+
+	resp := oauthClientFromDB(dbClient)
+	resp.ClientSecret = secret
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+// handlerGetOAuthClients lists the OAuth clients registered by the
+// authenticated user.
+func (cfg *apiConfig) handlerGetOAuthClients(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	dbClients, err := cfg.DB.GetOAuthClients(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get OAuth clients"))
+		return
+	}
+
+	clients := make([]OAuthClient, len(dbClients))
+	for i, dbClient := range dbClients {
+		clients[i] = oauthClientFromDB(dbClient)
+	}
+	respondWithJSON(w, http.StatusOK, clients)
+}
+
+// handlerDeleteOAuthClient deletes one of the authenticated user's OAuth
+// clients along with its unused authorization codes. Access tokens already
+// issued to it stay valid until they expire.
+func (cfg *apiConfig) handlerDeleteOAuthClient(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid clientID format")
+		return
+	}
+
+	deleted, err := cfg.DB.DeleteOAuthClient(r.Context(), database.DeleteOAuthClientParams{
+		ID:     clientID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to delete OAuth client"))
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, ErrCodeNotFound, "OAuth client not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// oauthError is an OAuth error response. Errors in an authorization
+// request are sent back to the client's redirect URI once it is known to
+// be registered; before that they are shown to the user.
+type oauthError struct {
+	Code        string
+	Description string
+	// Redirect is set once the redirect URI has been checked.
+	Redirect bool
+}
+
+// authorizeRequest is a validated request to /oauth/authorize.
+type authorizeRequest struct {
+	Client        database.OauthClient
+	RedirectURI   string
+	Scopes        []string
+	State         string
+	CodeChallenge string
+	// RedirectURISupplied is false when RedirectURI defaulted to the
+	// client's only registered URI.
+	RedirectURISupplied bool
+}
+
+// parseAuthorizeRequest validates the parameters of an authorization
+// request, taken from the query on GET and from the consent form on POST.
+func (cfg *apiConfig) parseAuthorizeRequest(r *http.Request) (authorizeRequest, *oauthError) {
+	req := authorizeRequest{State: r.FormValue("state")}
+
+	clientID, err := uuid.Parse(r.FormValue("client_id"))
+	if err != nil {
+		return req, &oauthError{Code: oauthErrInvalidRequest, Description: "Missing or invalid client_id"}
+	}
+	req.Client, err = cfg.DB.GetOAuthClient(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return req, &oauthError{Code: oauthErrInvalidClient, Description: "Unknown client"}
+	}
+	if err != nil {
+		log.Printf("Failed to get OAuth client %s: %v", clientID, err)
+		return req, &oauthError{Code: oauthErrServerError, Description: "Failed to get client"}
+	}
+
+	// The redirect URI must match a registered one exactly. It may only
+	// be left out when the client registered a single one.
+	req.RedirectURI = r.FormValue("redirect_uri")
+	req.RedirectURISupplied = req.RedirectURI != ""
+	if !req.RedirectURISupplied && len(req.Client.RedirectUris) == 1 {
+		req.RedirectURI = req.Client.RedirectUris[0]
+	}
+	registered := false
+	for _, uri := range req.Client.RedirectUris {
+		if uri == req.RedirectURI {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		return req, &oauthError{Code: oauthErrInvalidRequest, Description: "redirect_uri is not registered for this client"}
+	}
+
+	if r.FormValue("response_type") != "code" {
+		return req, &oauthError{Code: oauthErrUnsupportedResponse, Description: "response_type must be code", Redirect: true}
+	}
+	req.CodeChallenge = r.FormValue("code_challenge")
+	if req.CodeChallenge == "" || r.FormValue("code_challenge_method") != auth.PKCEMethodS256 {
+		return req, &oauthError{Code: oauthErrInvalidRequest, Description: "PKCE with code_challenge_method S256 is required", Redirect: true}
+	}
+	scopes, apiErr := validateScopes(strings.Fields(r.FormValue("scope")))
+	if apiErr != nil {
+		return req, &oauthError{Code: oauthErrInvalidScope, Description: apiErr.Message, Redirect: true}
+	}
+	req.Scopes = scopes
+	return req, nil
+}
+
+// consentPage asks the user to log in and approve an authorization
+// request. It is rendered with the request's parameters as hidden fields
+// so that the form can post them back.
+var consentPage = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+  <head><title>{{if .Fatal}}Authorization failed{{else}}Authorize {{.ClientName}}{{end}}</title></head>
+  <body>
+    {{if .Fatal}}
+    <h1>Authorization failed</h1>
+    <p>{{.Error}}</p>
+    {{else}}
+    <h1>Authorize {{.ClientName}}</h1>
+    <p>{{.ClientName}} would like to:</p>
+    <ul>
+      {{range .Scopes}}<li>{{.}}</li>
+      {{end}}
+    </ul>
+    {{if .Error}}<p><strong>{{.Error}}</strong></p>{{end}}
+    <form method="POST" action="/oauth/authorize">
+      {{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+      {{end}}
+      <label>Email <input type="email" name="email" value="{{.Email}}" autocomplete="username"></label>
+      <label>Password <input type="password" name="password" autocomplete="current-password"></label>
+      <button type="submit" name="action" value="allow">Allow</button>
+      <button type="submit" name="action" value="deny">Deny</button>
+    </form>
+    {{end}}
+  </body>
+</html>`))
+
+type consentPageData struct {
+	ClientName string
+	Scopes     []string
+	Params     map[string]string
+	Email      string
+	Error      string
+	// Fatal is set for errors that can't be sent back to the client.
+	Fatal bool
+}
+
+// renderConsentPage writes the consent page, which must not be framed by
+// other sites so that users can't be tricked into approving a request.
+func renderConsentPage(w http.ResponseWriter, status int, req authorizeRequest, data consentPageData) {
+	data.ClientName = req.Client.Name
+	for _, scope := range req.Scopes {
+		data.Scopes = append(data.Scopes, scopeDescriptions[scope])
+	}
+	data.Params = map[string]string{
+		"client_id":             req.Client.ID.String(),
+		"response_type":         "code",
+		"scope":                 strings.Join(req.Scopes, " "),
+		"state":                 req.State,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": auth.PKCEMethodS256,
+	}
+	// A defaulted redirect URI is left out so that it still counts as
+	// not supplied when the form is posted.
+	if req.RedirectURISupplied {
+		data.Params["redirect_uri"] = req.RedirectURI
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if err := consentPage.Execute(w, data); err != nil {
+		log.Printf("Failed to render consent page: %v", err)
+	}
+}
+
+// redirectToClient sends the user back to the client's redirect URI with
+// params added to its query.
+func redirectToClient(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		// Registered redirect URIs are validated, so this can't happen.
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Invalid redirect URI")
+		return
+	}
+	query := u.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	u.RawQuery = query.Encode()
+	http.Redirect(w, r, u.String(), http.StatusSeeOther)
+}
+
+// respondWithAuthorizeError redirects an authorization error back to the
+// client if its redirect URI is known to be safe and shows it to the user
+// otherwise.
+func respondWithAuthorizeError(w http.ResponseWriter, r *http.Request, req authorizeRequest, oauthErr *oauthError) {
+	if !oauthErr.Redirect {
+		status := http.StatusBadRequest
+		if oauthErr.Code == oauthErrServerError {
+			status = http.StatusInternalServerError
+		}
+		renderConsentPage(w, status, req, consentPageData{Error: oauthErr.Description, Fatal: true})
+		return
+	}
+
+	params := url.Values{
+		"error":             {oauthErr.Code},
+		"error_description": {oauthErr.Description},
+	}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	redirectToClient(w, r, req.RedirectURI, params)
+}
+
+// handlerOAuthAuthorize shows the consent page for an authorization
+// request from a third-party app.
+func (cfg *apiConfig) handlerOAuthAuthorize(w http.ResponseWriter, r *http.Request) {
+	req, oauthErr := cfg.parseAuthorizeRequest(r)
+	if oauthErr != nil {
+		respondWithAuthorizeError(w, r, req, oauthErr)
+		return
+	}
+	renderConsentPage(w, http.StatusOK, req, consentPageData{})
+}
+
+// handlerOAuthConsent handles the consent form. If the user logs in and
+// allows the request, they are sent back to the client with an
+// authorization code; if they deny it, with an access_denied error.
+func (cfg *apiConfig) handlerOAuthConsent(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxOAuthFormSize)
+	if err := r.ParseForm(); err != nil {
+		renderConsentPage(w, http.StatusBadRequest, authorizeRequest{}, consentPageData{Error: "Invalid form", Fatal: true})
+		return
+	}
+	req, oauthErr := cfg.parseAuthorizeRequest(r)
+	if oauthErr != nil {
+		respondWithAuthorizeError(w, r, req, oauthErr)
+		return
+	}
+
+	if r.PostFormValue("action") != "allow" {
+		respondWithAuthorizeError(w, r, req, &oauthError{Code: oauthErrAccessDenied, Description: "The user denied the request", Redirect: true})
+		return
+	}
+
+	email := r.PostFormValue("email")
+	user, err := cfg.DB.GetUserByEmail(r.Context(), email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Failed to get user for OAuth consent: %v", err)
+		renderConsentPage(w, http.StatusInternalServerError, req, consentPageData{Email: email, Error: "Something went wrong, please try again"})
+		return
+	}
+	if err != nil || auth.CheckPasswordHash(r.PostFormValue("password"), user.HashedPassword) != nil {
+		renderConsentPage(w, http.StatusUnauthorized, req, consentPageData{Email: email, Error: "Incorrect email or password"})
+		return
+	}
+	// Unlike logging in, authorizing an app doesn't cancel a pending
+	// deletion.
+	if user.DeletedAt.Valid {
+		renderConsentPage(w, http.StatusForbidden, req, consentPageData{Email: email, Error: "This account is scheduled for deletion. Log in to restore it first."})
+		return
+	}
+
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithAuthorizeError(w, r, req, &oauthError{Code: oauthErrServerError, Description: "Failed to issue authorization code", Redirect: true})
+		return
+	}
+	err = cfg.DB.CreateOAuthCode(r.Context(), database.CreateOAuthCodeParams{
+		CodeHash:            auth.HashToken(code),
+		ExpiresAt:           time.Now().Add(oauthCodeTTL),
+		ClientID:            req.Client.ID,
+		UserID:              user.ID,
+		RedirectUri:         req.RedirectURI,
+		RedirectUriSupplied: req.RedirectURISupplied,
+		Scopes:              req.Scopes,
+		CodeChallenge:       req.CodeChallenge,
+	})
+	if err != nil {
+		log.Printf("Failed to create OAuth code for client %s: %v", req.Client.ID, err)
+		respondWithAuthorizeError(w, r, req, &oauthError{Code: oauthErrServerError, Description: "Failed to issue authorization code", Redirect: true})
+		return
+	}
+
+	params := url.Values{"code": {code}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	redirectToClient(w, r, req.RedirectURI, params)
+}
+
+// respondWithOAuthError writes an error response from the token endpoint
+// in the format RFC 6749 requires.
+func respondWithOAuthError(w http.ResponseWriter, status int, code, description string) {
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+// handlerOAuthToken exchanges an authorization code for an access token
+// limited to the scopes the user approved. Clients authenticate with
+// their client_id, along with their secret if they are confidential,
+// either in the form or with HTTP Basic authentication.
+func (cfg *apiConfig) handlerOAuthToken(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+		Scope       string `json:"scope"`
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxOAuthFormSize)
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrInvalidRequest, "Invalid form")
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrUnsupportedGrant, "Only the authorization_code grant is supported")
+		return
+	}
+
+	rawClientID, secret, basic := r.BasicAuth()
+	if !basic {
+		rawClientID, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	clientID, err := uuid.Parse(rawClientID)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusUnauthorized, oauthErrInvalidClient, "Missing or invalid client_id")
+		return
+	}
+	client, err := cfg.DB.GetOAuthClient(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithOAuthError(w, http.StatusUnauthorized, oauthErrInvalidClient, "Unknown client")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get OAuth client %s: %v", clientID, err)
+		respondWithOAuthError(w, http.StatusInternalServerError, oauthErrServerError, "Failed to get client")
+		return
+	}
+	if client.HashedSecret.Valid &&
+		subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.HashedSecret.String)) != 1 {
+		respondWithOAuthError(w, http.StatusUnauthorized, oauthErrInvalidClient, "Invalid client credentials")
+		return
+	}
+
+	verifier := r.PostFormValue("code_verifier")
+	if !auth.ValidPKCEVerifier(verifier) {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrInvalidRequest, "Missing or invalid code_verifier")
+		return
+	}
+
+	// The code is used up even if the rest of the request turns out to be
+	// wrong, so that it can't be guessed at.
+	code, err := cfg.DB.ConsumeOAuthCode(r.Context(), auth.HashToken(r.PostFormValue("code")))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrInvalidGrant, "Invalid or used authorization code")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to consume OAuth code: %v", err)
+		respondWithOAuthError(w, http.StatusInternalServerError, oauthErrServerError, "Failed to check authorization code")
+		return
+	}
+	if code.ClientID != client.ID || time.Now().After(code.ExpiresAt) {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrInvalidGrant, "Invalid or expired authorization code")
+		return
+	}
+	// RFC 6749 section 4.1.3: redirect_uri is only required, and must
+	// then match, if it was included in the authorization request.
+	if code.RedirectUriSupplied && r.PostFormValue("redirect_uri") != code.RedirectUri {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrInvalidGrant, "redirect_uri does not match the authorization request")
+		return
+	}
+	if !auth.VerifyPKCE(verifier, code.CodeChallenge) {
+		respondWithOAuthError(w, http.StatusBadRequest, oauthErrInvalidGrant, "code_verifier does not match the code challenge")
+		return
+	}
+
+	accessToken, err := auth.MakeJWT(code.UserID, cfg.jwtSecret, oauthAccessTokenTTL, code.Scopes...)
+	if err != nil {
+		log.Printf("Failed to create OAuth access token: %v", err)
+		respondWithOAuthError(w, http.StatusInternalServerError, oauthErrServerError, "Failed to create access token")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, response{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(oauthAccessTokenTTL.Seconds()),
+		Scope:       strings.Join(code.Scopes, " "),
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+// maxScheduleAhead is how far in the future a chirp can be scheduled.
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+// validatePublishAt checks that a requested publish time lies between now
+// and maxScheduleAhead from now.
+func validatePublishAt(publishAt, now time.Time) *APIError {
+	if !publishAt.After(now) {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidation, "publish_at must be in the future")
+	}
+	if publishAt.After(now.Add(maxScheduleAhead)) {
+		return &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "publish_at is too far in the future",
+			Details: map[string]any{"max_days": int(maxScheduleAhead / (24 * time.Hour))},
+		}
+	}
+	return nil
+}
+
+// handlerGetScheduledChirps returns the authenticated user's chirps that
+// are waiting to be published, soonest first.
+func (cfg *apiConfig) handlerGetScheduledChirps(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	dbChirps, err := cfg.DB.GetScheduledChirps(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get scheduled chirps"))
+		return
+	}
+
+	chirps := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirps[i] = chirpFromDB(dbChirp)
+	}
+
+	if err := cfg.hydrateChirps(r.Context(), chirps, userID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp details"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
+
+// handlerCancelScheduledChirp deletes one of the authenticated user's
+// scheduled chirps before it is published. Published chirps are deleted
+// through DELETE /api/chirps/{chirpID} instead.
+func (cfg *apiConfig) handlerCancelScheduledChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
+		return
+	}
+
+	attachedMedia, err := cfg.DB.GetMediaForChirps(r.Context(), []uuid.UUID{chirpID})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to cancel chirp"))
+		return
+	}
+
+	cancelled, err := cfg.DB.CancelScheduledChirp(r.Context(), database.CancelScheduledChirpParams{
+		ID:     chirpID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to cancel chirp"))
+		return
+	}
+	if cancelled == 0 {
+		respondWithError(w, http.StatusNotFound, ErrCodeNotFound, "Scheduled chirp not found")
+		return
+	}
+
+	for _, m := range attachedMedia {
+		cfg.deleteBlobs(r.Context(), m.StorageKey, m.ThumbnailKey)
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
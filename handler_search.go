@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"html"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BabichevDima/goServer/internal/chirptext"
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxSearchQueryLength = 256
+
+// ts_headline wraps matches in these private use characters rather than
+// HTML tags, so that the body can be escaped before the marks are added.
+const (
+	headlineStartSel = "\uE000"
+	headlineStopSel  = "\uE001"
+	headlineOptions  = "StartSel=" + headlineStartSel + ", StopSel=" + headlineStopSel + ", HighlightAll=true"
+)
+
+var headlineReplacer = strings.NewReplacer(
+	headlineStartSel, "<mark>",
+	headlineStopSel, "</mark>",
+)
+
+// searchCursor marks a position in search results, which are ordered by
+// (rank, created_at, id) rather than by (created_at, id) like other lists.
+type searchCursor struct {
+	Rank      float32
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// firstSearchCursor sorts after every search result. No rank comes close
+// to math.MaxFloat32.
+var firstSearchCursor = searchCursor{
+	Rank:      math.MaxFloat32,
+	CreatedAt: latestCursor.CreatedAt,
+	ID:        latestCursor.ID,
+}
+
+// String encodes the cursor as an opaque URL-safe token.
+func (c searchCursor) String() string {
+	raw := strconv.FormatFloat(float64(c.Rank), 'g', -1, 32) + "|" + pageCursor{CreatedAt: c.CreatedAt, ID: c.ID}.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// parseSearchCursor decodes a token produced by searchCursor.String.
+func parseSearchCursor(s string) (searchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return searchCursor{}, errors.New("invalid cursor encoding")
+	}
+
+	rankStr, rest, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return searchCursor{}, errors.New("malformed cursor")
+	}
+
+	rank, err := strconv.ParseFloat(rankStr, 32)
+	if err != nil {
+		return searchCursor{}, errors.New("invalid cursor rank")
+	}
+
+	position, err := parseCursor(rest)
+	if err != nil {
+		return searchCursor{}, err
+	}
+
+	return searchCursor{Rank: float32(rank), CreatedAt: position.CreatedAt, ID: position.ID}, nil
+}
+
+// SearchResult is a chirp matching a search query. Headline is the body
+// with HTML special characters escaped and matches wrapped in <mark>.
+type SearchResult struct {
+	Chirp
+	Rank     float32 `json:"rank"`
+	Headline string  `json:"headline"`
+}
+
+// SearchPage is a page of search results with the cursor for the next page.
+type SearchPage struct {
+	Results    []SearchResult `json:"results"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// handlerSearchChirps runs a full-text search over chirp bodies. The "q"
+// parameter uses web search syntax: quoted phrases, "or" and "-" to
+// exclude a word. Results can be limited to one author with "author" and
+// to a date range with "since" and "until".
+func (cfg *apiConfig) handlerSearchChirps(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	q := chirptext.Normalize(strings.TrimSpace(query.Get("q")))
+	if q == "" {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Search query is required")
+		return
+	}
+	if utf8.RuneCountInString(q) > maxSearchQueryLength {
+		respondWithAPIError(w, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "Search query is too long",
+			Details: map[string]any{"max": maxSearchQueryLength},
+		})
+		return
+	}
+
+	viewerID := cfg.optionalUserID(r)
+	params := database.SearchChirpsParams{
+		Query:           q,
+		HeadlineOptions: headlineOptions,
+		ViewerID:        viewerID,
+	}
+
+	if author := query.Get("author"); author != "" {
+		authorID, apiErr := cfg.userIDByHandle(r.Context(), author)
+		if apiErr != nil {
+			respondWithAPIError(w, apiErr)
+			return
+		}
+		params.AuthorID = uuid.NullUUID{UUID: authorID, Valid: true}
+	}
+
+	var err error
+	if params.Since, err = parseSearchTime(query.Get("since"), false); err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid since, use RFC 3339 or YYYY-MM-DD")
+		return
+	}
+	if params.Until, err = parseSearchTime(query.Get("until"), true); err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid until, use RFC 3339 or YYYY-MM-DD")
+		return
+	}
+
+	limit, apiErr := parseLimit(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	params.Limit = limit
+
+	cursor := firstSearchCursor
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cursor, err = parseSearchCursor(cursorStr)
+		if err != nil {
+			respondWithAPIError(w, &APIError{Status: http.StatusBadRequest, Code: ErrCodeValidation, Message: "Invalid cursor", Err: err})
+			return
+		}
+	}
+	params.BeforeRank = cursor.Rank
+	params.BeforeCreatedAt = cursor.CreatedAt
+	params.BeforeID = cursor.ID
+
+	rows, err := cfg.DB.SearchChirps(r.Context(), params)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to search chirps"))
+		return
+	}
+
+	chirps := make([]Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = chirpFromDB(database.Chirp{
+			ID:                row.ID,
+			CreatedAt:         row.CreatedAt,
+			UpdatedAt:         row.UpdatedAt,
+			Body:              row.Body,
+			UserID:            row.UserID,
+			ParentID:          row.ParentID,
+			Kind:              row.Kind,
+			ReferencedChirpID: row.ReferencedChirpID,
+		})
+	}
+
+	if err := cfg.hydrateChirps(r.Context(), chirps, viewerID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp details"))
+		return
+	}
+
+	page := SearchPage{Results: make([]SearchResult, len(rows))}
+	for i, row := range rows {
+		page.Results[i] = SearchResult{
+			Chirp:    chirps[i],
+			Rank:     row.Rank,
+			Headline: headlineReplacer.Replace(html.EscapeString(row.Headline)),
+		}
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		page.NextCursor = searchCursor{Rank: last.Rank, CreatedAt: last.CreatedAt, ID: last.ID}.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// parseSearchTime parses an optional RFC 3339 timestamp or YYYY-MM-DD date.
+// A date used as the end of a range includes that whole day.
+func parseSearchTime(s string, end bool) (sql.NullTime, error) {
+	if s == "" {
+		return sql.NullTime{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
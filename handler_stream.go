@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/stream"
+	"github.com/google/uuid"
+)
+
+const (
+	// streamKeepAlive is how often an idle stream sends a comment so that
+	// proxies don't close the connection.
+	streamKeepAlive = 25 * time.Second
+	// streamRetry tells EventSource clients how long to wait before
+	// reconnecting.
+	streamRetry = 3 * time.Second
+)
+
+// streamResetEvent tells a resuming client that it missed events which
+// are no longer available, so it should reload chirps over the REST API.
+const streamResetEvent = "reset"
+
+// handlerChirpStream streams new and deleted chirps as Server-Sent Events.
+// Clients that reconnect with a Last-Event-ID header first receive the
+// events they missed. Authenticated viewers don't receive chirps by users
+// they blocked or muted or who blocked them, as of when they connected.
+func (cfg *apiConfig) handlerChirpStream(w http.ResponseWriter, r *http.Request) {
+	excluded, err := cfg.excludedAuthors(r.Context(), cfg.optionalUserID(r))
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to open stream"))
+		return
+	}
+
+	sub, missed, ok := cfg.hub.Subscribe(r.Header.Get("Last-Event-ID"))
+	defer cfg.hub.Unsubscribe(sub)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stop nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	if !ok {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", streamResetEvent)
+	}
+	for _, event := range missed {
+		if !excluded[event.AuthorID] {
+			writeStreamEvent(w, event)
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		log.Printf("Chirp stream can't be flushed: %v", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-cfg.shutdown:
+			// The client reconnects to another instance or once this
+			// one is back, and resumes from its last event.
+			return
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+		case event, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects
+				// and resumes from its last event.
+				return
+			}
+			if excluded[event.AuthorID] {
+				continue
+			}
+			writeStreamEvent(w, event)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// excludedAuthors returns the set of users whose chirps viewerID doesn't
+// see because of a block or mute. It is empty for anonymous viewers.
+func (cfg *apiConfig) excludedAuthors(ctx context.Context, viewerID uuid.UUID) (map[uuid.UUID]bool, error) {
+	excluded := make(map[uuid.UUID]bool)
+	if viewerID == uuid.Nil {
+		return excluded, nil
+	}
+	ids, err := cfg.DB.GetExcludedAuthorIDs(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		excluded[id] = true
+	}
+	return excluded, nil
+}
+
+// writeStreamEvent writes event in the text/event-stream format. Event
+// data is single-line JSON, so it fits in one data field.
+func writeStreamEvent(w io.Writer, event stream.Event) {
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
+
+// publishChirpCreated announces a newly published chirp to stream clients
+// and the author's webhooks. Failures only affect these notifications, so
+// they are logged rather than reported to the client that created the
+// chirp.
+func (cfg *apiConfig) publishChirpCreated(ctx context.Context, chirp Chirp) {
+	if err := cfg.hub.Publish(ctx, stream.EventChirpCreated, chirp.UserID, chirp); err != nil {
+		log.Printf("Failed to publish chirp %s: %v", chirp.ID, err)
+	}
+	cfg.enqueueWebhooks(ctx, stream.EventChirpCreated, chirp.UserID, chirp)
+}
+
+// publishChirpDeleted announces a deleted chirp to stream clients and the
+// author's webhooks.
+func (cfg *apiConfig) publishChirpDeleted(ctx context.Context, chirpID, authorID uuid.UUID) {
+	data := map[string]uuid.UUID{"id": chirpID}
+	if err := cfg.hub.Publish(ctx, stream.EventChirpDeleted, authorID, data); err != nil {
+		log.Printf("Failed to publish deletion of chirp %s: %v", chirpID, err)
+	}
+	cfg.enqueueWebhooks(ctx, stream.EventChirpDeleted, authorID, data)
+}
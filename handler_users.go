@@ -0,0 +1,333 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BabichevDima/goServer/internal/auth"
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 2048
+
+	handleRulesMessage = "Handle must be 3-30 characters of letters, digits or underscores"
+)
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+// reservedHandles can't be claimed because they collide with routes
+// under /api/users or would be confusing to other users.
+var reservedHandles = map[string]bool{
+	"admin":  true,
+	"api":    true,
+	"chirpy": true,
+	"export": true,
+	"me":     true,
+}
+
+// PublicProfile is the part of a user that anyone may see.
+// It never includes the email address.
+type PublicProfile struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Handle         string    `json:"handle"`
+	DisplayName    string    `json:"display_name"`
+	Bio            string    `json:"bio"`
+	AvatarURL      string    `json:"avatar_url"`
+	ChirpCount     int64     `json:"chirp_count"`
+	FollowerCount  int64     `json:"follower_count"`
+	FollowingCount int64     `json:"following_count"`
+}
+
+// handlerPatchUser partially updates the authenticated user's profile.
+// Every field is optional; only the fields present in the body are changed.
+// Changing the email or password requires the current password.
+func (cfg *apiConfig) handlerPatchUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	type parameters struct {
+		Email           *string `json:"email"`
+		Password        *string `json:"password"`
+		CurrentPassword string  `json:"current_password"`
+		DisplayName     *string `json:"display_name"`
+		Bio             *string `json:"bio"`
+		AvatarURL       *string `json:"avatar_url"`
+		Handle          *string `json:"handle"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
+		return
+	}
+
+	if params.Email != nil && *params.Email == "" {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Email can't be empty")
+		return
+	}
+	if params.Password != nil && *params.Password == "" {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Password can't be empty")
+		return
+	}
+	if params.DisplayName != nil && utf8.RuneCountInString(*params.DisplayName) > maxDisplayNameLength {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Display name is too long")
+		return
+	}
+	if params.Bio != nil && utf8.RuneCountInString(*params.Bio) > maxBioLength {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Bio is too long")
+		return
+	}
+	if params.AvatarURL != nil && *params.AvatarURL != "" && !isValidAvatarURL(*params.AvatarURL) {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Avatar URL must be an absolute http(s) URL")
+		return
+	}
+
+	if params.Handle != nil {
+		normalized, ok := normalizeHandle(*params.Handle)
+		if !ok {
+			respondWithError(w, http.StatusBadRequest, ErrCodeValidation, handleRulesMessage)
+			return
+		}
+		params.Handle = &normalized
+	}
+
+	update := database.UpdateUserProfileParams{
+		DisplayName: nullString(params.DisplayName),
+		Bio:         nullString(params.Bio),
+		AvatarUrl:   nullString(params.AvatarURL),
+		Handle:      nullString(params.Handle),
+		ID:          userID,
+	}
+
+	if params.Email != nil || params.Password != nil {
+		if params.CurrentPassword == "" {
+			respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Current password is required to change email or password")
+			return
+		}
+
+		currentUser, err := cfg.DB.GetUserByID(r.Context(), userID)
+		if err != nil {
+			respondWithAPIError(w, dbError(err, "Failed to get user data"))
+			return
+		}
+
+		if err := auth.CheckPasswordHash(params.CurrentPassword, currentUser.HashedPassword); err != nil {
+			respondWithError(w, http.StatusForbidden, ErrCodeForbidden, "Current password is incorrect")
+			return
+		}
+
+		update.Email = nullString(params.Email)
+
+		if params.Password != nil {
+			hashPassword, err := auth.HashPassword(*params.Password)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to hash password")
+				return
+			}
+			update.HashedPassword = sql.NullString{String: hashPassword, Valid: true}
+		}
+	}
+
+	user, err := cfg.DB.UpdateUserProfile(r.Context(), update)
+	if err != nil {
+		respondWithAPIError(w, userConflictError(err, "Failed to update user"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, User{
+		ID:          user.ID.String(),
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		Handle:      user.Handle.String,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarUrl,
+	})
+}
+
+// handlerGetUserProfile returns the public profile of the user with the given handle.
+func (cfg *apiConfig) handlerGetUserProfile(w http.ResponseWriter, r *http.Request) {
+	handle, ok := normalizeHandle(r.PathValue("handle"))
+	if !ok {
+		respondWithError(w, http.StatusNotFound, ErrCodeNotFound, "User not found")
+		return
+	}
+
+	profile, err := cfg.DB.GetPublicProfileByHandle(r.Context(), sql.NullString{String: handle, Valid: true})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "User not found"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, PublicProfile{
+		ID:             profile.ID,
+		CreatedAt:      profile.CreatedAt,
+		Handle:         profile.Handle.String,
+		DisplayName:    profile.DisplayName,
+		Bio:            profile.Bio,
+		AvatarURL:      profile.AvatarUrl,
+		ChirpCount:     profile.ChirpCount,
+		FollowerCount:  profile.FollowerCount,
+		FollowingCount: profile.FollowingCount,
+	})
+}
+
+// handlerDeleteUser deletes the authenticated user's account after
+// re-checking their password. When a deletion grace period is configured
+// the account is only marked as deleted and purged later; logging in
+// before then restores it.
+func (cfg *apiConfig) handlerDeleteUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
+		return
+	}
+
+	if params.Password == "" {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Password is required")
+		return
+	}
+
+	user, err := cfg.DB.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get user data"))
+		return
+	}
+
+	if err := auth.CheckPasswordHash(params.Password, user.HashedPassword); err != nil {
+		respondWithError(w, http.StatusForbidden, ErrCodeForbidden, "Password is incorrect")
+		return
+	}
+
+	if cfg.deletionGracePeriod == 0 {
+		leftovers, err := cfg.getAccountLeftovers(r.Context(), userID)
+		if err != nil {
+			respondWithAPIError(w, dbError(err, "Failed to delete user"))
+			return
+		}
+		if err := cfg.DB.DeleteUser(r.Context(), userID); err != nil {
+			respondWithAPIError(w, dbError(err, "Failed to delete user"))
+			return
+		}
+		cfg.cleanUpDeletedAccount(r.Context(), userID, leftovers)
+		respondWithJSON(w, http.StatusNoContent, nil)
+		return
+	}
+
+	if err := cfg.DB.RevokeUserRefreshTokens(r.Context(), userID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to revoke refresh tokens"))
+		return
+	}
+
+	if err := cfg.DB.SoftDeleteUser(r.Context(), userID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to delete user"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, struct {
+		PurgeAfter time.Time `json:"purge_after"`
+	}{
+		PurgeAfter: time.Now().Add(cfg.deletionGracePeriod),
+	})
+}
+
+// handlerExportUser returns a JSON archive of the authenticated user's
+// profile and all of their chirps.
+func (cfg *apiConfig) handlerExportUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	user, err := cfg.DB.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get user data"))
+		return
+	}
+
+	dbChirps, err := cfg.DB.GetChirpsByUserID(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirps"))
+		return
+	}
+
+	chirps := make([]Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirps[i] = chirpFromDB(dbChirp)
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="chirpy-export.json"`)
+	respondWithJSON(w, http.StatusOK, struct {
+		ExportedAt time.Time `json:"exported_at"`
+		Profile    User      `json:"profile"`
+		Chirps     []Chirp   `json:"chirps"`
+	}{
+		ExportedAt: time.Now().UTC(),
+		Profile: User{
+			ID:          user.ID.String(),
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+			Email:       user.Email,
+			Handle:      user.Handle.String,
+			DisplayName: user.DisplayName,
+			Bio:         user.Bio,
+			AvatarURL:   user.AvatarUrl,
+		},
+		Chirps: chirps,
+	})
+}
+
+// normalizeHandle lowercases a handle, strips an optional leading "@"
+// and reports whether the result is a valid, non-reserved handle.
+func normalizeHandle(s string) (string, bool) {
+	handle := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "@"))
+	if !handlePattern.MatchString(handle) || reservedHandles[handle] {
+		return "", false
+	}
+	return handle, true
+}
+
+// parseHandleList parses a comma-separated list of handles.
+// It reports false if any entry is not a valid handle.
+func parseHandleList(s string) ([]string, bool) {
+	var handles []string
+	for _, part := range strings.Split(s, ",") {
+		handle, ok := normalizeHandle(part)
+		if !ok {
+			return nil, false
+		}
+		handles = append(handles, handle)
+	}
+	return handles, true
+}
+
+// isValidAvatarURL reports whether s is an absolute http or https URL.
+func isValidAvatarURL(s string) bool {
+	if len(s) > maxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// nullString converts an optional JSON string into a sql.NullString
+// so that COALESCE keeps the current column value when it is absent.
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/BabichevDima/goServer/internal/stream"
+	"github.com/BabichevDima/goServer/internal/webhook"
+	"github.com/google/uuid"
+)
+
+const (
+	maxWebhooksPerUser  = 10
+	maxWebhookURLLength = 2048
+)
+
+// webhookEvents are the event types a webhook can subscribe to. They are
+// named like the real-time stream's events.
+var webhookEvents = []string{stream.EventChirpCreated, stream.EventChirpDeleted}
+
+// Webhook is a URL that receives events about its owner's chirps. Secret
+// is only returned when the webhook is created.
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+}
+
+func webhookFromDB(dbWebhook database.Webhook) Webhook {
+	return Webhook{
+		ID:        dbWebhook.ID,
+		CreatedAt: dbWebhook.CreatedAt,
+		URL:       dbWebhook.Url,
+		Events:    dbWebhook.Events,
+	}
+}
+
+// WebhookDelivery is the log entry of one event sent to a webhook.
+// NextAttemptAt is set while the delivery is pending.
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	ResponseStatus *int32          `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+func webhookDeliveryFromDB(d database.WebhookDelivery) WebhookDelivery {
+	delivery := WebhookDelivery{
+		ID:        d.ID,
+		CreatedAt: d.CreatedAt,
+		EventType: d.EventType,
+		Status:    d.Status,
+		Attempts:  d.Attempts,
+		LastError: d.LastError,
+		Payload:   json.RawMessage(d.Payload),
+	}
+	if d.LastAttemptAt.Valid {
+		delivery.LastAttemptAt = &d.LastAttemptAt.Time
+	}
+	if d.Status == webhookDeliveryPending {
+		delivery.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.ResponseStatus.Valid {
+		delivery.ResponseStatus = &d.ResponseStatus.Int32
+	}
+	return delivery
+}
+
+// webhookPayload is the body of every webhook delivery.
+type webhookPayload struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// enqueueWebhooks queues deliveries of an event about userID's chirps to
+// the user's webhooks, each with a deliverWebhookJob to send it.
+// Failures are logged rather than failing the action behind the event.
+func (cfg *apiConfig) enqueueWebhooks(ctx context.Context, eventType string, userID uuid.UUID, data any) {
+	payload, err := json.Marshal(webhookPayload{
+		ID:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		log.Printf("Failed to encode %s webhook payload: %v", eventType, err)
+		return
+	}
+	err = cfg.withTx(ctx, func(q *database.Queries) error {
+		deliveryIDs, err := q.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+			EventType: eventType,
+			Payload:   string(payload),
+			UserID:    userID,
+		})
+		if err != nil {
+			return err
+		}
+		for _, id := range deliveryIDs {
+			if err := enqueueWebhookAttempt(ctx, q, id, time.Now()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to queue %s webhooks for user %s: %v", eventType, userID, err)
+	}
+}
+
+// validateWebhook checks a webhook's URL and events and returns the
+// events without duplicates.
+func validateWebhook(rawURL string, events []string) ([]string, *APIError) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil {
+		return nil, newAPIError(http.StatusBadRequest, ErrCodeValidation, "Webhook URL must be an absolute http or https URL")
+	}
+	if len(rawURL) > maxWebhookURLLength {
+		return nil, newAPIError(http.StatusBadRequest, ErrCodeValidation, "Webhook URL is too long")
+	}
+
+	if len(events) == 0 {
+		return nil, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "At least one event is required",
+			Details: map[string]any{"events": webhookEvents},
+		}
+	}
+	unique := make([]string, 0, len(events))
+	for _, event := range events {
+		if !slices.Contains(webhookEvents, event) {
+			return nil, &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    ErrCodeValidation,
+				Message: "Unknown webhook event",
+				Details: map[string]any{"event": event, "events": webhookEvents},
+			}
+		}
+		if !slices.Contains(unique, event) {
+			unique = append(unique, event)
+		}
+	}
+	return unique, nil
+}
+
+// handlerCreateWebhook registers a webhook for the authenticated user. The
+// response includes the secret that signs its deliveries, which isn't
+// shown again.
+func (cfg *apiConfig) handlerCreateWebhook(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	type parameters struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
+		return
+	}
+
+	events, apiErr := validateWebhook(params.URL, params.Events)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	count, err := cfg.DB.CountWebhooks(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to create webhook"))
+		return
+	}
+	if count >= maxWebhooksPerUser {
+		respondWithAPIError(w, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "Too many webhooks",
+			Details: map[string]any{"limit": maxWebhooksPerUser},
+		})
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		respondWithAPIError(w, &APIError{Status: http.StatusInternalServerError, Code: ErrCodeInternal, Message: "Failed to create webhook", Err: err})
+		return
+	}
+
+	dbWebhook, err := cfg.DB.CreateWebhook(r.Context(), database.CreateWebhookParams{
+		UserID: userID,
+		Url:    params.URL,
+		Secret: secret,
+		Events: events,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to create webhook"))
+		return
+	}
+
+	resp := webhookFromDB(dbWebhook)
+	resp.Secret = dbWebhook.Secret
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+// handlerGetWebhooks lists the authenticated user's webhooks.
+func (cfg *apiConfig) handlerGetWebhooks(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	dbWebhooks, err := cfg.DB.GetWebhooks(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get webhooks"))
+		return
+	}
+
+	webhooks := make([]Webhook, len(dbWebhooks))
+	for i, dbWebhook := range dbWebhooks {
+		webhooks[i] = webhookFromDB(dbWebhook)
+	}
+	respondWithJSON(w, http.StatusOK, webhooks)
+}
+
+// handlerDeleteWebhook removes one of the authenticated user's webhooks
+// along with its delivery log. Pending deliveries are dropped.
+func (cfg *apiConfig) handlerDeleteWebhook(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid webhookID format")
+		return
+	}
+
+	deleted, err := cfg.DB.DeleteWebhook(r.Context(), database.DeleteWebhookParams{
+		ID:     webhookID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to delete webhook"))
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, ErrCodeNotFound, "Webhook not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// handlerGetWebhookDeliveries returns the delivery log of one of the
+// authenticated user's webhooks, newest first, one page at a time.
+func (cfg *apiConfig) handlerGetWebhookDeliveries(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid webhookID format")
+		return
+	}
+
+	cursor, limit, apiErr := parsePage(r)
+	if apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+	cursor = cursor.orLatest()
+
+	_, err = cfg.DB.GetWebhook(r.Context(), database.GetWebhookParams{ID: webhookID, UserID: userID})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Webhook not found"))
+		return
+	}
+
+	rows, err := cfg.DB.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{
+		WebhookID:       webhookID,
+		BeforeCreatedAt: cursor.CreatedAt,
+		BeforeID:        cursor.ID,
+		Limit:           limit,
+	})
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get webhook deliveries"))
+		return
+	}
+
+	type response struct {
+		Deliveries []WebhookDelivery `json:"deliveries"`
+		NextCursor string            `json:"next_cursor,omitempty"`
+	}
+	resp := response{Deliveries: make([]WebhookDelivery, len(rows))}
+	for i, row := range rows {
+		resp.Deliveries[i] = webhookDeliveryFromDB(row)
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		resp.NextCursor = pageCursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
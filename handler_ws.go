@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/auth"
+	"github.com/BabichevDima/goServer/internal/chirptext"
+	"github.com/BabichevDima/goServer/internal/stream"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is how long a client may stay silent before the
+	// connection is considered dead. Pings are sent more often than that.
+	wsPongWait     = 60 * time.Second
+	wsPingInterval = wsPongWait * 9 / 10
+	// wsMaxMessageSize is the largest message a client may send.
+	wsMaxMessageSize = 4 << 10
+	// wsSendBuffer is how many messages may queue up for a client before
+	// it is disconnected as too slow.
+	wsSendBuffer       = 64
+	wsMaxSubscriptions = 50
+)
+
+// WebSocket channels a client can subscribe to: every chirp, the chirps
+// of one user ("user:<handle>") or the chirps with a hashtag
+// ("hashtag:<tag>").
+const (
+	wsChannelGlobal        = "global"
+	wsChannelUserPrefix    = "user:"
+	wsChannelHashtagPrefix = "hashtag:"
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// wsClientMessage is a message sent by a WebSocket client. Type is one of
+// "subscribe", "unsubscribe" or "ping".
+type wsClientMessage struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+}
+
+// wsServerMessage is a message sent to a WebSocket client. Type is one of
+// "subscribed", "unsubscribed", "event", "pong" or "error".
+type wsServerMessage struct {
+	Type    string          `json:"type"`
+	Channel string          `json:"channel,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	ID      string          `json:"id,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// wsSubscription is a channel a client subscribed to.
+type wsSubscription struct {
+	authorID uuid.UUID // set for user channels
+	tag      string    // set for hashtag channels
+}
+
+// matches reports whether an event belongs to the subscription. tags are
+// the hashtags of the chirp the event is about. Deletions don't carry the
+// deleted chirp's body, so they are sent to every hashtag subscription
+// and clients ignore the IDs they don't know.
+func (s wsSubscription) matches(event stream.Event, tags []string) bool {
+	switch {
+	case s.authorID != uuid.Nil:
+		return event.AuthorID == s.authorID
+	case s.tag != "":
+		if event.Type == stream.EventChirpDeleted {
+			return true
+		}
+		for _, tag := range tags {
+			if tag == s.tag {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// wsClient is one WebSocket connection. Reads happen on the handler's
+// goroutine, writes on writeLoop and hub events are matched on eventLoop.
+type wsClient struct {
+	cfg      *apiConfig
+	conn     *websocket.Conn
+	send     chan wsServerMessage
+	done     chan struct{}
+	excluded map[uuid.UUID]bool
+
+	mu            sync.Mutex
+	subscriptions map[string]wsSubscription
+}
+
+// handlerWebSocket upgrades the request to a WebSocket connection over
+// which clients subscribe to live chirp events. It takes the same access
+// token as the REST API, in the Authorization header or, for browsers that
+// can't set headers on WebSocket requests, the access_token query parameter.
+func (cfg *apiConfig) handlerWebSocket(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		respondWithError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Authentication required")
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, ErrCodeInvalidToken, "Invalid access token")
+		return
+	}
+	if apiErr := cfg.requireActiveUser(r.Context(), userID); apiErr != nil {
+		respondWithAPIError(w, apiErr)
+		return
+	}
+
+	excluded, err := cfg.excludedAuthors(r.Context(), userID)
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to open connection"))
+		return
+	}
+
+	// The upgrader has already responded if this fails.
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	client := &wsClient{
+		cfg:           cfg,
+		conn:          conn,
+		send:          make(chan wsServerMessage, wsSendBuffer),
+		done:          make(chan struct{}),
+		excluded:      excluded,
+		subscriptions: make(map[string]wsSubscription),
+	}
+
+	sub, _, _ := cfg.hub.Subscribe("")
+	defer cfg.hub.Unsubscribe(sub)
+
+	go client.writeLoop()
+	go client.eventLoop(sub)
+	client.readLoop(r)
+}
+
+// readLoop handles client messages until the connection fails or closes.
+func (c *wsClient) readLoop(r *http.Request) {
+	defer close(c.done)
+	defer c.conn.Close()
+
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var msg wsClientMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				c.enqueue(wsServerMessage{Type: "error", Error: "Invalid message"})
+				continue
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		switch msg.Type {
+		case "ping":
+			c.enqueue(wsServerMessage{Type: "pong"})
+		case "subscribe":
+			if apiErr := c.subscribe(r, msg.Channel); apiErr != nil {
+				c.enqueue(wsServerMessage{Type: "error", Channel: msg.Channel, Error: apiErr.Message})
+				continue
+			}
+			c.enqueue(wsServerMessage{Type: "subscribed", Channel: msg.Channel})
+		case "unsubscribe":
+			c.mu.Lock()
+			delete(c.subscriptions, msg.Channel)
+			c.mu.Unlock()
+			c.enqueue(wsServerMessage{Type: "unsubscribed", Channel: msg.Channel})
+		default:
+			c.enqueue(wsServerMessage{Type: "error", Error: "Unknown message type"})
+		}
+	}
+}
+
+func (c *wsClient) subscribe(r *http.Request, channel string) *APIError {
+	var sub wsSubscription
+	switch {
+	case channel == wsChannelGlobal:
+	case strings.HasPrefix(channel, wsChannelUserPrefix):
+		userID, apiErr := c.cfg.userIDByHandle(r.Context(), strings.TrimPrefix(channel, wsChannelUserPrefix))
+		if apiErr != nil {
+			return apiErr
+		}
+		sub.authorID = userID
+	case strings.HasPrefix(channel, wsChannelHashtagPrefix):
+		sub.tag = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(channel, wsChannelHashtagPrefix), "#"))
+		if sub.tag == "" {
+			return newAPIError(http.StatusBadRequest, ErrCodeValidation, "Invalid hashtag")
+		}
+	default:
+		return newAPIError(http.StatusBadRequest, ErrCodeValidation, "Unknown channel")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.subscriptions[channel]; !ok && len(c.subscriptions) >= wsMaxSubscriptions {
+		return newAPIError(http.StatusBadRequest, ErrCodeValidation, "Too many subscriptions")
+	}
+	c.subscriptions[channel] = sub
+	return nil
+}
+
+// eventLoop forwards hub events to the channels they match.
+func (c *wsClient) eventLoop(sub *stream.Subscription) {
+	for {
+		select {
+		case <-c.done:
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// The hub dropped this client for falling behind, unless
+				// the connection is already closing.
+				select {
+				case <-c.done:
+				default:
+					c.closeSlow()
+				}
+				return
+			}
+			if c.excluded[event.AuthorID] {
+				continue
+			}
+			c.deliver(event)
+		}
+	}
+}
+
+func (c *wsClient) deliver(event stream.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var tags []string
+	if event.Type == stream.EventChirpCreated && c.hasHashtagSubscription() {
+		var chirp struct {
+			Body string `json:"body"`
+		}
+		if err := json.Unmarshal(event.Data, &chirp); err == nil {
+			tags = chirptext.Hashtags(chirp.Body)
+		}
+	}
+
+	for channel, sub := range c.subscriptions {
+		if sub.matches(event, tags) {
+			c.enqueue(wsServerMessage{
+				Type:    "event",
+				Channel: channel,
+				Event:   event.Type,
+				ID:      event.ID,
+				Data:    event.Data,
+			})
+		}
+	}
+}
+
+func (c *wsClient) hasHashtagSubscription() bool {
+	for _, sub := range c.subscriptions {
+		if sub.tag != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue queues msg for writeLoop. A client that doesn't keep up with its
+// messages is disconnected rather than buffered without bound.
+func (c *wsClient) enqueue(msg wsServerMessage) {
+	select {
+	case c.send <- msg:
+	default:
+		c.closeSlow()
+	}
+}
+
+// closeSlow disconnects a client that fell behind, telling it to
+// reconnect later.
+func (c *wsClient) closeSlow() {
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "Client is too slow"),
+		time.Now().Add(wsWriteWait))
+	c.conn.Close()
+}
+
+// writeLoop is the only goroutine that writes data messages to the
+// connection. It also pings the client to detect dead connections and
+// closes the connection when the server shuts down.
+func (c *wsClient) writeLoop() {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.cfg.shutdown:
+			// Shutdown doesn't wait for hijacked connections, so close
+			// them here. The client reconnects to another instance or
+			// once this one is back.
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "Server is shutting down"),
+				time.Now().Add(wsWriteWait))
+			c.conn.Close()
+			return
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				c.conn.Close()
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
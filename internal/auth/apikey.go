@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to spot.
+const APIKeyPrefix = "chirpy_"
+
+// apiKeyIDLength is the length of the random part of an API key that is
+// stored in plain text to identify it.
+const apiKeyIDLength = 8
+
+// MakeAPIKey returns a new API key and its public prefix, which is shown
+// to users to tell their keys apart. Only the key's hash should be stored.
+func MakeAPIKey() (key, prefix string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	secret := hex.EncodeToString(b)
+	key = APIKeyPrefix + secret
+	return key, key[:len(APIKeyPrefix)+apiKeyIDLength], nil
+}
+
+// HashAPIKey returns the hash under which an API key is stored.
+func HashAPIKey(key string) string {
+	return HashToken(key)
+}
+
+// HashToken returns the hash under which a long, random secret such as an
+// API key or an OAuth authorization code is stored. Such secrets can't be
+// guessed, so a fast hash is enough and keeps lookups cheap.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKeyAuth reports whether the request authenticates with an API key
+// rather than a bearer token.
+func IsAPIKeyAuth(headers http.Header) bool {
+	return strings.HasPrefix(headers.Get("Authorization"), "ApiKey ")
+}
+
+// GetAPIKey returns the key from an "Authorization: ApiKey <key>" header.
+func GetAPIKey(headers http.Header) (string, error) {
+	authHeader := headers.Get("Authorization")
+	if authHeader == "" {
+		return "", ErrNoAuthHeader
+	}
+
+	scheme, key, ok := strings.Cut(authHeader, " ")
+	if !ok || scheme != "ApiKey" || !strings.HasPrefix(key, APIKeyPrefix) {
+		return "", ErrMalformedAuthHeader
+	}
+	return key, nil
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeAPIKey(t *testing.T) {
+	key, prefix, err := MakeAPIKey()
+	require.NoError(t, err)
+	assert.True(t, len(key) > len(prefix))
+	assert.Equal(t, key[:len(prefix)], prefix)
+	assert.Regexp(t, `^chirpy_[0-9a-f]{8}$`, prefix)
+
+	other, _, err := MakeAPIKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, HashAPIKey(key), HashAPIKey(other))
+	assert.Equal(t, HashAPIKey(key), HashAPIKey(key))
+}
+
+func TestGetAPIKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		header        string
+		expectedKey   string
+		expectedError error
+	}{
+		{name: "Valid key", header: "ApiKey chirpy_abc", expectedKey: "chirpy_abc"},
+		{name: "No auth header", header: "", expectedError: ErrNoAuthHeader},
+		{name: "Bearer token", header: "Bearer chirpy_abc", expectedError: ErrMalformedAuthHeader},
+		{name: "Not an API key", header: "ApiKey abc", expectedError: ErrMalformedAuthHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.header != "" {
+				headers.Set("Authorization", tt.header)
+			}
+			key, err := GetAPIKey(headers)
+			assert.Equal(t, tt.expectedKey, key)
+			assert.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
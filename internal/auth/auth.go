@@ -21,6 +21,22 @@ const (
 	TokenIssuer  = "chirpy"
 )
 
+var (
+	// ErrNoAuthHeader is returned when the request has no Authorization header
+	ErrNoAuthHeader = errors.New("authorization header is missing")
+	// ErrMalformedAuthHeader is returned when the Authorization header is not "Bearer <token>"
+	ErrMalformedAuthHeader = errors.New("malformed authorization header")
+	// ErrScopedToken is returned by ValidateJWT for tokens issued to OAuth clients
+	ErrScopedToken = errors.New("token is limited to scopes")
+)
+
+// Claims are the claims of an access token. Scope is set on tokens issued
+// to OAuth clients and lists, space-separated, what they may do.
+type Claims struct {
+	jwt.RegisteredClaims
+	Scope string `json:"scope,omitempty"`
+}
+
 // HashPassword хеширует пароль с использованием bcrypt
 func HashPassword(password string) (string, error) {
 	// GenerateFromPassword возвращает bcrypt хеш пароля
@@ -36,13 +52,18 @@ func CheckPasswordHash(password, hash string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error){
+// MakeJWT creates an access token for userID. Tokens with scopes are
+// limited to them; tokens without are full sessions.
+func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration, scopes ...string) (string, error){
 	// Create the Claims
-	claims := &jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		Subject:   userID.String(),
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			Subject:   userID.String(),
+		},
+		Scope: strings.Join(scopes, " "),
 	}
 
 	// Create the token
@@ -57,10 +78,25 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 	return signedToken, nil
 }
 
+// ValidateJWT validates a session access token and returns its user ID.
+// Tokens limited to scopes are rejected with ErrScopedToken.
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	userID, scopes, err := ValidateScopedJWT(tokenString, tokenSecret)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(scopes) > 0 {
+		return uuid.Nil, ErrScopedToken
+	}
+	return userID, nil
+}
+
+// ValidateScopedJWT validates an access token and returns its user ID and
+// scopes, which are empty for session tokens.
+func ValidateScopedJWT(tokenString, tokenSecret string) (uuid.UUID, []string, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
-		&jwt.RegisteredClaims{},
+		&Claims{},
 		func(token *jwt.Token) (interface{}, error) {
 			// check sign method
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -71,38 +107,38 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	)
 
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid token: %w", err)
+		return uuid.Nil, nil, fmt.Errorf("invalid token: %w", err)
 	}
 
 	// check claims
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	claims, ok := token.Claims.(*Claims)
 	if !ok || !token.Valid {
-		return uuid.Nil, fmt.Errorf("invalid token claims")
+		return uuid.Nil, nil, fmt.Errorf("invalid token claims")
 	}
 
 	// check issuer
 	if claims.Issuer != TokenIssuer {
-		return uuid.Nil, fmt.Errorf("invalid issuer")
+		return uuid.Nil, nil, fmt.Errorf("invalid issuer")
 	}
 
 	// get userID from Subject
 	userID, err := uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID in token")
+		return uuid.Nil, nil, fmt.Errorf("invalid user ID in token")
 	}
 
-	return userID, nil
+	return userID, strings.Fields(claims.Scope), nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
 	authHeader := headers.Get("Authorization")
 	if authHeader == "" {
-		return "", errors.New("authorization header is missing")
+		return "", ErrNoAuthHeader
 	}
 
 	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		return "", errors.New("malformed authorization header")
+	if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
+		return "", ErrMalformedAuthHeader
 	}
 
 	return parts[1], nil
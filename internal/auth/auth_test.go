@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"net/http"
 	"testing"
 	"time"
 
@@ -35,6 +36,29 @@ func TestJWT(t *testing.T) {
 	assert.Contains(t, err.Error(), "expired")
 }
 
+func TestScopedJWT(t *testing.T) {
+	secret := "test-secret"
+	userID := uuid.New()
+
+	token, err := MakeJWT(userID, secret, time.Hour, "read", "chirps:write")
+	assert.NoError(t, err)
+
+	parsedID, scopes, err := ValidateScopedJWT(token, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, parsedID)
+	assert.Equal(t, []string{"read", "chirps:write"}, scopes)
+
+	// Scoped tokens aren't sessions
+	_, err = ValidateJWT(token, secret)
+	assert.ErrorIs(t, err, ErrScopedToken)
+
+	session, err := MakeJWT(userID, secret, time.Hour)
+	assert.NoError(t, err)
+	_, scopes, err = ValidateScopedJWT(session, secret)
+	assert.NoError(t, err)
+	assert.Empty(t, scopes)
+}
+
 func TestGetBearerToken(t *testing.T) {
 	tests := []struct {
 		name          string
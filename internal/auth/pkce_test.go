@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// The example from RFC 7636, appendix B.
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	assert.Equal(t, challenge, PKCEChallenge(verifier))
+	assert.True(t, VerifyPKCE(verifier, challenge))
+	assert.False(t, VerifyPKCE(verifier+"x", challenge))
+	assert.False(t, VerifyPKCE(verifier, challenge[1:]))
+}
+
+func TestValidPKCEVerifier(t *testing.T) {
+	assert.True(t, ValidPKCEVerifier(strings.Repeat("a", 43)))
+	assert.True(t, ValidPKCEVerifier(strings.Repeat("a-._~", 25)))
+	assert.False(t, ValidPKCEVerifier(strings.Repeat("a", 42)))
+	assert.False(t, ValidPKCEVerifier(strings.Repeat("a", 129)))
+	assert.False(t, ValidPKCEVerifier(strings.Repeat("a", 42)+"+"))
+}
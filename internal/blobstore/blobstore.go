@@ -0,0 +1,113 @@
+// Package blobstore stores uploaded files such as chirp images.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var (
+	// ErrNotFound is returned when a blob does not exist.
+	ErrNotFound = errors.New("blobstore: blob not found")
+	// ErrInvalidKey is returned for keys that are empty or contain
+	// characters other than letters, digits, ".", "_" and "-".
+	ErrInvalidKey = errors.New("blobstore: invalid key")
+)
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// BlobStore stores blobs under flat string keys. Implementations must be
+// safe for concurrent use.
+type BlobStore interface {
+	// Put stores the contents of r under key, replacing any existing blob.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the blob stored under key. The caller must close it.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob
+	// is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// FS is a BlobStore that keeps each blob in a file in a local directory.
+type FS struct {
+	root string
+}
+
+// NewFS returns an FS rooted at dir, creating the directory if needed.
+func NewFS(dir string) (*FS, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("blobstore: create %s: %w", dir, err)
+	}
+	return &FS{root: dir}, nil
+}
+
+func (s *FS) path(key string) (string, error) {
+	if !keyPattern.MatchString(key) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.root, key), nil
+}
+
+// Put writes the blob to a temporary file and renames it into place, so
+// readers never see a partially written blob.
+func (s *FS) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.root, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("blobstore: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("blobstore: write %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("blobstore: write %s: %w", key, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("blobstore: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("blobstore: write %s: %w", key, err)
+	}
+	return nil
+}
+
+// Open returns the blob's file, which also implements io.Seeker.
+func (s *FS) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("blobstore: open %s: %w", key, err)
+	}
+	return f, nil
+}
+
+func (s *FS) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("blobstore: delete %s: %w", key, err)
+	}
+	return nil
+}
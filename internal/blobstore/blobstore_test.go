@@ -0,0 +1,47 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFS(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFS(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(ctx, "image.png", strings.NewReader("data")))
+
+	f, err := store.Open(ctx, "image.png")
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	f.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	require.NoError(t, store.Delete(ctx, "image.png"))
+	_, err = store.Open(ctx, "image.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, store.Delete(ctx, "image.png"), "deleting a missing blob")
+}
+
+func TestFSInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFS(t.TempDir())
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "../secret", "a/b", ".hidden"} {
+		t.Run(key, func(t *testing.T) {
+			assert.ErrorIs(t, store.Put(ctx, key, strings.NewReader("x")), ErrInvalidKey)
+			_, err := store.Open(ctx, key)
+			assert.ErrorIs(t, err, ErrInvalidKey)
+			assert.ErrorIs(t, store.Delete(ctx, key), ErrInvalidKey)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Package chirptext normalizes and measures chirp bodies and extracts
+// hashtags and mentions from them.
+package chirptext
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// MaxHashtagLength is the longest hashtag that is indexed
+	MaxHashtagLength = 50
+)
+
+var (
+	// A hashtag is "#" followed by letters, digits or underscores and must not
+	// be glued to a preceding word, so "C#" or "a#b" are not hashtags.
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+	// A mention is "@" followed by a handle and must not be glued to a
+	// preceding word, so email addresses are not mentions.
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([A-Za-z0-9_]{3,30})\b`)
+	onlyDigits     = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Hashtags returns the distinct hashtags in body, lowercased and without
+// the leading "#", in order of first appearance.
+// Tags made only of digits (like "#1") and overly long tags are ignored.
+func Hashtags(body string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, match := range hashtagPattern.FindAllStringSubmatch(body, -1) {
+		tag := strings.ToLower(match[1])
+		if onlyDigits.MatchString(tag) || len([]rune(tag)) > MaxHashtagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// Mentions returns the distinct handles mentioned in body, lowercased and
+// without the leading "@", in order of first appearance.
+func Mentions(body string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		handle := strings.ToLower(match[1])
+		if seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+	}
+	return handles
+}
@@ -0,0 +1,82 @@
+package chirptext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{
+			name:     "Single hashtag",
+			body:     "Hello #Chirpy",
+			expected: []string{"chirpy"},
+		},
+		{
+			name:     "Duplicates are removed",
+			body:     "#go is fun, #Go is fast",
+			expected: []string{"go"},
+		},
+		{
+			name:     "Unicode hashtag",
+			body:     "Привет #мир!",
+			expected: []string{"мир"},
+		},
+		{
+			name:     "Glued to a word",
+			body:     "I write C# and a#b",
+			expected: nil,
+		},
+		{
+			name:     "Only digits",
+			body:     "We are #1",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Hashtags(tt.body))
+		})
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{
+			name:     "Single mention",
+			body:     "Thanks @Alice_1!",
+			expected: []string{"alice_1"},
+		},
+		{
+			name:     "Several mentions",
+			body:     "@bob and @carol, and again @Bob",
+			expected: []string{"bob", "carol"},
+		},
+		{
+			name:     "Email is not a mention",
+			body:     "mail me at bob@example.com",
+			expected: nil,
+		},
+		{
+			name:     "Too short",
+			body:     "hi @al",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Mentions(tt.body))
+		})
+	}
+}
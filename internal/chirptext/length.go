@@ -0,0 +1,65 @@
+package chirptext
+
+import (
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const zeroWidthJoiner = '\u200d'
+
+// Normalize returns body in Unicode Normalization Form C, so that text
+// which looks the same is stored, measured and matched the same way
+// whether accents were typed as one code point or as a letter followed
+// by a combining mark.
+func Normalize(body string) string {
+	return norm.NFC.String(body)
+}
+
+// Length returns the number of user-perceived characters in body.
+// It approximates Unicode grapheme clusters: combining marks, variation
+// selectors, emoji modifiers and characters joined by a zero width joiner
+// count towards the character they attach to, and a pair of regional
+// indicators (a flag) counts as one character.
+func Length(body string) int {
+	n := 0
+	joined := false
+	pendingRegional := false
+	for _, r := range body {
+		if r == zeroWidthJoiner {
+			joined = true
+			continue
+		}
+		if joined || extendsCharacter(r) {
+			joined = false
+			continue
+		}
+		if pendingRegional && isRegionalIndicator(r) {
+			pendingRegional = false
+			continue
+		}
+		n++
+		pendingRegional = isRegionalIndicator(r)
+	}
+	return n
+}
+
+// extendsCharacter reports whether r modifies the character before it
+// rather than starting a new one.
+func extendsCharacter(r rune) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F: // variation selectors
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // emoji skin tone modifiers
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tag characters used by subdivision flags
+		return true
+	}
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
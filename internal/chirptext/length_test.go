@@ -0,0 +1,62 @@
+package chirptext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	// "e" followed by a combining acute accent becomes a single "é".
+	assert.Equal(t, "caf\u00e9", Normalize("cafe\u0301"))
+}
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{
+			name:     "ASCII",
+			body:     "Hello, Chirpy!",
+			expected: 14,
+		},
+		{
+			name:     "Cyrillic",
+			body:     "Привет, мир",
+			expected: 11,
+		},
+		{
+			name:     "Combining mark",
+			body:     "cafe\u0301",
+			expected: 4,
+		},
+		{
+			name:     "Emoji with skin tone",
+			body:     "\U0001F44D\U0001F3FD",
+			expected: 1,
+		},
+		{
+			name:     "Emoji joined by ZWJ",
+			body:     "\U0001F469\u200d\U0001F4BB coding",
+			expected: 8,
+		},
+		{
+			name:     "Flags",
+			body:     "\U0001F1FA\U0001F1E6\U0001F1EF\U0001F1F5",
+			expected: 2,
+		},
+		{
+			name:     "Emoji with variation selector",
+			body:     "\u2764\ufe0f",
+			expected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Length(tt.body))
+		})
+	}
+}
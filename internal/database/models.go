@@ -11,12 +11,141 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiKey struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	Prefix     string
+	HashedKey  string
+	Scopes     []string
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Body              string
+	UserID            uuid.UUID
+	ParentID          uuid.NullUUID
+	Kind              string
+	ReferencedChirpID uuid.NullUUID
+	SearchVector      interface{}
+	HiddenAt          sql.NullTime
+	Status            string
+	PublishAt         sql.NullTime
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Job struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	Kind        string
+	Payload     string
+	Status      string
+	Attempts    int32
+	MaxAttempts int32
+	RunAt       time.Time
+	LockedUntil sql.NullTime
+	LastError   string
+	UniqueKey   sql.NullString
+}
+
+type Media struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	ChirpID      uuid.NullUUID
+	Position     int32
+	ContentType  string
+	Width        int32
+	Height       int32
+	ByteSize     int32
+	StorageKey   string
+	ThumbnailKey string
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Notification struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
 	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	Type      string
+	ChirpID   uuid.NullUUID
+	ReadAt    sql.NullTime
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash            string
+	CreatedAt           time.Time
+	ExpiresAt           time.Time
+	ClientID            uuid.UUID
+	UserID              uuid.UUID
+	RedirectUri         string
+	RedirectUriSupplied bool
+	Scopes              []string
+	CodeChallenge       string
+	UsedAt              sql.NullTime
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	Name         string
+	RedirectUris []string
+	HashedSecret sql.NullString
 }
 
 type RefreshToken struct {
@@ -28,10 +157,63 @@ type RefreshToken struct {
 	UserID    uuid.UUID
 }
 
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ChirpID    uuid.UUID
+	ReporterID uuid.UUID
+	Reason     string
+	Details    string
+	Status     string
+	ResolvedAt sql.NullTime
+	ResolvedBy uuid.NullUUID
+}
+
 type User struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 	Email          string
 	HashedPassword string
+	DisplayName    string
+	Bio            string
+	AvatarUrl      string
+	Handle         sql.NullString
+	DeletedAt      sql.NullTime
+	Role           string
+}
+
+type Webhook struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Url       string
+	Secret    string
+	Events    []string
+}
+
+type WebhookDeadLetter struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	DeliveryID uuid.UUID
+	WebhookID  uuid.UUID
+	EventType  string
+	Payload    string
+	Attempts   int32
+	LastError  string
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	WebhookID      uuid.UUID
+	EventType      string
+	Payload        string
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastAttemptAt  sql.NullTime
+	ResponseStatus sql.NullInt32
+	LastError      string
 }
@@ -7,27 +7,46 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (body, user_id)
+INSERT INTO chirps (body, user_id, parent_id, kind, referenced_chirp_id, publish_at, status)
 VALUES (
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    CASE WHEN $6::timestamp IS NULL THEN 'published' ELSE 'scheduled' END
     )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, parent_id, kind, referenced_chirp_id, search_vector, hidden_at, status, publish_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body              string
+	UserID            uuid.UUID
+	ParentID          uuid.NullUUID
+	Kind              string
+	ReferencedChirpID uuid.NullUUID
+	PublishAt         sql.NullTime
 }
 
+// A chirp with a publish_at is stored as scheduled.
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ParentID,
+		arg.Kind,
+		arg.ReferencedChirpID,
+		arg.PublishAt,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -35,6 +54,13 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.Kind,
+		&i.ReferencedChirpID,
+		&i.SearchVector,
+		&i.HiddenAt,
+		&i.Status,
+		&i.PublishAt,
 	)
 	return i, err
 }
@@ -66,17 +92,19 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 }
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (email, hashed_password)
+INSERT INTO users (email, hashed_password, handle)
 VALUES (
     $1,
-    $2
+    $2,
+    $3
     )
-RETURNING id, created_at, updated_at, email
+RETURNING id, created_at, updated_at, email, handle
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 type CreateUserRow struct {
@@ -84,16 +112,18 @@ type CreateUserRow struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Email     string
+	Handle    sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i CreateUserRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Email,
+		&i.Handle,
 	)
 	return i, err
 }
@@ -117,13 +147,37 @@ func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) (int64
 	return result.RowsAffected()
 }
 
-const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users
 WHERE id = $1
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirp, id)
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const getChirp = `-- name: GetChirp :one
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.kind, chirps.referenced_chirp_id, chirps.search_vector, chirps.hidden_at, chirps.status, chirps.publish_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1
+AND users.deleted_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $2 AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2)
+)
+`
+
+type GetChirpParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.UUID
+}
+
+// Returns the chirp unless a block separates its author and the viewer or
+// the author's account is pending deletion.
+func (q *Queries) GetChirp(ctx context.Context, arg GetChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirp, arg.ID, arg.ViewerID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -131,17 +185,141 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.Kind,
+		&i.ReferencedChirpID,
+		&i.SearchVector,
+		&i.HiddenAt,
+		&i.Status,
+		&i.PublishAt,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.kind, chirps.referenced_chirp_id, chirps.search_vector, chirps.hidden_at, chirps.status, chirps.publish_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE users.deleted_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $1 AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $1)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = $1 AND mutes.muted_id = chirps.user_id
+)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = $1)
+AND chirps.status = 'published'
+ORDER BY chirps.created_at
+`
+
+// Skips chirps hidden from the viewer by a block or mute, and those of
+// accounts pending deletion.
+func (q *Queries) GetChirps(ctx context.Context, viewerID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.Kind,
+			&i.ReferencedChirpID,
+			&i.SearchVector,
+			&i.HiddenAt,
+			&i.Status,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByAuthorHandles = `-- name: GetChirpsByAuthorHandles :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.kind, chirps.referenced_chirp_id, chirps.search_vector, chirps.hidden_at, chirps.status, chirps.publish_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE users.handle = ANY($1::text[])
+AND users.deleted_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $2 AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = $2 AND mutes.muted_id = chirps.user_id
+)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = $2)
+AND chirps.status = 'published'
+ORDER BY chirps.created_at
+`
+
+type GetChirpsByAuthorHandlesParams struct {
+	Handles  []string
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetChirpsByAuthorHandles(ctx context.Context, arg GetChirpsByAuthorHandlesParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByAuthorHandles, pq.Array(arg.Handles), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.Kind,
+			&i.ReferencedChirpID,
+			&i.SearchVector,
+			&i.HiddenAt,
+			&i.Status,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByUserID = `-- name: GetChirpsByUserID :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, kind, referenced_chirp_id, search_vector, hidden_at, status, publish_at FROM chirps
+WHERE user_id = $1
 ORDER BY created_at
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByUserID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -155,6 +333,13 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.Kind,
+			&i.ReferencedChirpID,
+			&i.SearchVector,
+			&i.HiddenAt,
+			&i.Status,
+			&i.PublishAt,
 		); err != nil {
 			return nil, err
 		}
@@ -169,8 +354,82 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
+const getPublicProfileByHandle = `-- name: GetPublicProfileByHandle :one
+SELECT
+    users.id,
+    users.created_at,
+    users.handle,
+    users.display_name,
+    users.bio,
+    users.avatar_url,
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = users.id AND chirps.status = 'published') AS chirp_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = users.id) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id) AS following_count
+FROM users
+WHERE users.handle = $1
+AND users.deleted_at IS NULL
+`
+
+type GetPublicProfileByHandleRow struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	Handle         sql.NullString
+	DisplayName    string
+	Bio            string
+	AvatarUrl      string
+	ChirpCount     int64
+	FollowerCount  int64
+	FollowingCount int64
+}
+
+func (q *Queries) GetPublicProfileByHandle(ctx context.Context, handle sql.NullString) (GetPublicProfileByHandleRow, error) {
+	row := q.db.QueryRowContext(ctx, getPublicProfileByHandle, handle)
+	var i GetPublicProfileByHandleRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.ChirpCount,
+		&i.FollowerCount,
+		&i.FollowingCount,
+	)
+	return i, err
+}
+
+const getPurgeableUserIDs = `-- name: GetPurgeableUserIDs :many
+SELECT id FROM users
+WHERE deleted_at IS NOT NULL
+AND deleted_at < $1::timestamptz
+`
+
+func (q *Queries) GetPurgeableUserIDs(ctx context.Context, cutoff time.Time) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getPurgeableUserIDs, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password FROM users
+SELECT id, created_at, updated_at, email, hashed_password, display_name, bio, avatar_url, handle, deleted_at, role FROM users
 WHERE email = $1
 `
 
@@ -183,12 +442,18 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.DeletedAt,
+		&i.Role,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, email, hashed_password FROM users
+SELECT id, created_at, updated_at, email, hashed_password, display_name, bio, avatar_url, handle, deleted_at, role FROM users
 WHERE id = $1
 `
 
@@ -201,6 +466,12 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+		&i.DeletedAt,
+		&i.Role,
 	)
 	return i, err
 }
@@ -226,6 +497,67 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Ge
 	return i, err
 }
 
+const purgeDeletedUser = `-- name: PurgeDeletedUser :execrows
+DELETE FROM users
+WHERE id = $1
+AND deleted_at IS NOT NULL
+AND deleted_at < $2::timestamptz
+`
+
+type PurgeDeletedUserParams struct {
+	ID     uuid.UUID
+	Cutoff time.Time
+}
+
+// Deletes the user only if they are still past the cutoff, so an
+// account restored since GetPurgeableUserIDs ran is kept.
+func (q *Queries) PurgeDeletedUser(ctx context.Context, arg PurgeDeletedUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedUser, arg.ID, arg.Cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const purgeRefreshTokens = `-- name: PurgeRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE token IN (
+    SELECT token FROM refresh_tokens
+    WHERE expires_at < NOW()
+    OR revoked_at < $1::timestamptz
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+`
+
+type PurgeRefreshTokensParams struct {
+	RevokedBefore time.Time
+	Limit         int32
+}
+
+// Deletes up to limit refresh tokens that expired or were revoked before
+// revoked_before. Deleting in batches keeps each statement's locks short.
+func (q *Queries) PurgeRefreshTokens(ctx context.Context, arg PurgeRefreshTokensParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeRefreshTokens, arg.RevokedBefore, arg.Limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreUser = `-- name: RestoreUser :exec
+UPDATE users
+SET
+    deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) RestoreUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, restoreUser, id)
+	return err
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET 
@@ -240,6 +572,33 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
 	return err
 }
 
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID)
+	return err
+}
+
+const softDeleteUser = `-- name: SoftDeleteUser :exec
+UPDATE users
+SET
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) SoftDeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, softDeleteUser, id)
+	return err
+}
+
 const updateUserCredentials = `-- name: UpdateUserCredentials :one
 UPDATE users 
 SET 
@@ -274,3 +633,62 @@ func (q *Queries) UpdateUserCredentials(ctx context.Context, arg UpdateUserCrede
 	)
 	return i, err
 }
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET
+    email = COALESCE($1, email),
+    hashed_password = COALESCE($2, hashed_password),
+    display_name = COALESCE($3, display_name),
+    bio = COALESCE($4, bio),
+    avatar_url = COALESCE($5, avatar_url),
+    handle = COALESCE($6, handle),
+    updated_at = NOW()
+WHERE id = $7
+RETURNING id, created_at, updated_at, email, display_name, bio, avatar_url, handle
+`
+
+type UpdateUserProfileParams struct {
+	Email          sql.NullString
+	HashedPassword sql.NullString
+	DisplayName    sql.NullString
+	Bio            sql.NullString
+	AvatarUrl      sql.NullString
+	Handle         sql.NullString
+	ID             uuid.UUID
+}
+
+type UpdateUserProfileRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Email       string
+	DisplayName string
+	Bio         string
+	AvatarUrl   string
+	Handle      sql.NullString
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UpdateUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.Email,
+		arg.HashedPassword,
+		arg.DisplayName,
+		arg.Bio,
+		arg.AvatarUrl,
+		arg.Handle,
+		arg.ID,
+	)
+	var i UpdateUserProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.Handle,
+	)
+	return i, err
+}
@@ -0,0 +1,121 @@
+// Package jobs runs background work from a queue stored in Postgres.
+//
+// Jobs are enqueued with Kind.Enqueue, usually in the same transaction as
+// the change that needs them, and run by a Worker with the handler
+// registered for their kind. A job that fails is retried with exponential
+// backoff until it runs out of attempts. Workers claim jobs with
+// SELECT ... FOR UPDATE SKIP LOCKED, so any number of them can share the
+// queue, and a job whose worker dies is run again once its lock expires.
+// Handlers must therefore be idempotent.
+//
+// Recurring jobs, registered with Every, are kept in the same queue: a
+// single job per kind is rescheduled after each run instead of deleted.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultMaxAttempts is how many times a job runs before it is marked
+	// failed, unless EnqueueOptions says otherwise.
+	DefaultMaxAttempts = 5
+
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+)
+
+// Enqueuer stores new jobs. It is implemented by *database.Queries, so
+// jobs can be enqueued inside a transaction.
+type Enqueuer interface {
+	EnqueueJob(ctx context.Context, arg database.EnqueueJobParams) (uuid.UUID, error)
+}
+
+// Kind names a kind of job whose payload is a T. The payload is stored as
+// JSON.
+type Kind[T any] string
+
+// EnqueueOptions changes when and how often a job runs.
+type EnqueueOptions struct {
+	// RunAt is when the job runs first. The zero value means now.
+	RunAt time.Time
+	// MaxAttempts defaults to DefaultMaxAttempts.
+	MaxAttempts int
+	// UniqueKey, if set, keeps the job from being enqueued while another
+	// job with the same key exists, whatever its status.
+	UniqueKey string
+}
+
+// Enqueue stores a job of kind k with payload and returns its ID. If the
+// job has a UniqueKey that is already taken, nothing is stored and the ID
+// is uuid.Nil.
+func (k Kind[T]) Enqueue(ctx context.Context, q Enqueuer, payload T, opts EnqueueOptions) (uuid.UUID, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("jobs: encode %s payload: %w", k, err)
+	}
+	if opts.RunAt.IsZero() {
+		opts.RunAt = time.Now()
+	}
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	id, err := q.EnqueueJob(ctx, database.EnqueueJobParams{
+		Kind:        string(k),
+		Payload:     string(data),
+		RunAt:       opts.RunAt.UTC(),
+		MaxAttempts: int32(opts.MaxAttempts),
+		UniqueKey:   sql.NullString{String: opts.UniqueKey, Valid: opts.UniqueKey != ""},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	return id, err
+}
+
+// permanentError marks an error that retrying won't fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that the job that returned it is marked failed
+// right away instead of being retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Backoff returns how long to wait before retrying a job that has failed
+// attempts times: 10s, 20s, 40s and so on, capped at 1h.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := baseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
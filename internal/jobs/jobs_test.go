@@ -0,0 +1,343 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore is an in-memory job queue.
+type memStore struct {
+	mu   sync.Mutex
+	jobs map[uuid.UUID]*database.Job
+}
+
+func newMemStore() *memStore {
+	return &memStore{jobs: make(map[uuid.UUID]*database.Job)}
+}
+
+func (s *memStore) EnqueueJob(ctx context.Context, arg database.EnqueueJobParams) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if arg.UniqueKey.Valid {
+		for _, job := range s.jobs {
+			if job.UniqueKey == arg.UniqueKey {
+				return uuid.Nil, sql.ErrNoRows
+			}
+		}
+	}
+	job := &database.Job{
+		ID:          uuid.New(),
+		Kind:        arg.Kind,
+		Payload:     arg.Payload,
+		Status:      "pending",
+		MaxAttempts: arg.MaxAttempts,
+		RunAt:       arg.RunAt,
+		UniqueKey:   arg.UniqueKey,
+	}
+	s.jobs[job.ID] = job
+	return job.ID, nil
+}
+
+func (s *memStore) ClaimJobs(ctx context.Context, arg database.ClaimJobsParams) ([]database.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []database.Job
+	for _, job := range s.jobs {
+		if len(claimed) == int(arg.Limit) {
+			break
+		}
+		if job.Status != "pending" || job.RunAt.After(time.Now()) {
+			continue
+		}
+		job.Status = "running"
+		job.Attempts++
+		job.LockedUntil = arg.LockedUntil
+		claimed = append(claimed, *job)
+	}
+	return claimed, nil
+}
+
+func (s *memStore) DeleteJob(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *memStore) RetryJob(ctx context.Context, arg database.RetryJobParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[arg.ID]
+	job.Status = "pending"
+	job.RunAt = arg.RunAt
+	job.LastError = arg.LastError
+	return nil
+}
+
+func (s *memStore) RescheduleJob(ctx context.Context, arg database.RescheduleJobParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[arg.ID]
+	job.Status = "pending"
+	job.Attempts = 0
+	job.RunAt = arg.RunAt
+	job.LastError = arg.LastError
+	return nil
+}
+
+func (s *memStore) FailJob(ctx context.Context, arg database.FailJobParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[arg.ID]
+	job.Status = "failed"
+	job.LastError = arg.LastError
+	return nil
+}
+
+func (s *memStore) all() []database.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []database.Job
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	return jobs
+}
+
+func (s *memStore) get(id uuid.UUID) (database.Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return database.Job{}, false
+	}
+	return *job, true
+}
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+const greet = Kind[greeting]("greet")
+
+func startWorker(t *testing.T, w *Worker) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+	stop = func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func TestWorkerRunsJobs(t *testing.T) {
+	store := newMemStore()
+	w := NewWorker(store, Options{PollInterval: 10 * time.Millisecond})
+	got := make(chan string, 1)
+	Handle(w, greet, func(ctx context.Context, g greeting) error {
+		got <- g.Name
+		return nil
+	})
+
+	id, err := greet.Enqueue(context.Background(), store, greeting{Name: "chirpy"}, EnqueueOptions{})
+	require.NoError(t, err)
+	startWorker(t, w)
+
+	select {
+	case name := <-got:
+		assert.Equal(t, "chirpy", name)
+	case <-time.After(time.Second):
+		t.Fatal("job didn't run")
+	}
+	require.Eventually(t, func() bool {
+		_, ok := store.get(id)
+		return !ok
+	}, time.Second, 10*time.Millisecond, "succeeded job wasn't deleted")
+}
+
+func TestWorkerRetriesWithBackoff(t *testing.T) {
+	store := newMemStore()
+	w := NewWorker(store, Options{PollInterval: 10 * time.Millisecond})
+	Handle(w, greet, func(ctx context.Context, g greeting) error {
+		return errors.New("try again")
+	})
+
+	id, err := greet.Enqueue(context.Background(), store, greeting{}, EnqueueOptions{})
+	require.NoError(t, err)
+	startWorker(t, w)
+
+	require.Eventually(t, func() bool {
+		job, _ := store.get(id)
+		return job.Attempts == 1 && job.Status == "pending"
+	}, time.Second, 10*time.Millisecond)
+	job, _ := store.get(id)
+	assert.Equal(t, "try again", job.LastError)
+	assert.WithinDuration(t, time.Now().Add(Backoff(1)), job.RunAt, time.Second)
+}
+
+func TestWorkerFailsJobs(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		err         error
+	}{
+		{name: "out of attempts", maxAttempts: 1, err: errors.New("boom")},
+		{name: "permanent error", maxAttempts: 5, err: Permanent(errors.New("boom"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemStore()
+			w := NewWorker(store, Options{PollInterval: 10 * time.Millisecond})
+			Handle(w, greet, func(ctx context.Context, g greeting) error {
+				return tt.err
+			})
+
+			id, err := greet.Enqueue(context.Background(), store, greeting{}, EnqueueOptions{MaxAttempts: tt.maxAttempts})
+			require.NoError(t, err)
+			startWorker(t, w)
+
+			require.Eventually(t, func() bool {
+				job, _ := store.get(id)
+				return job.Status == "failed"
+			}, time.Second, 10*time.Millisecond)
+			job, _ := store.get(id)
+			assert.Equal(t, "boom", job.LastError)
+		})
+	}
+}
+
+func TestWorkerRecoversPanics(t *testing.T) {
+	store := newMemStore()
+	w := NewWorker(store, Options{PollInterval: 10 * time.Millisecond})
+	Handle(w, greet, func(ctx context.Context, g greeting) error {
+		panic("oops")
+	})
+
+	id, err := greet.Enqueue(context.Background(), store, greeting{}, EnqueueOptions{})
+	require.NoError(t, err)
+	startWorker(t, w)
+
+	require.Eventually(t, func() bool {
+		job, _ := store.get(id)
+		return job.Status == "failed"
+	}, time.Second, 10*time.Millisecond)
+	job, _ := store.get(id)
+	assert.Contains(t, job.LastError, "oops")
+}
+
+func TestWorkerShutdown(t *testing.T) {
+	t.Run("waits for running jobs", func(t *testing.T) {
+		store := newMemStore()
+		w := NewWorker(store, Options{PollInterval: 10 * time.Millisecond})
+		started := make(chan struct{})
+		Handle(w, greet, func(ctx context.Context, g greeting) error {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		})
+
+		id, err := greet.Enqueue(context.Background(), store, greeting{}, EnqueueOptions{})
+		require.NoError(t, err)
+		stop := startWorker(t, w)
+		<-started
+		stop()
+
+		_, ok := store.get(id)
+		assert.False(t, ok, "job didn't finish before shutdown")
+	})
+
+	t.Run("cancels jobs after the timeout", func(t *testing.T) {
+		store := newMemStore()
+		w := NewWorker(store, Options{PollInterval: 10 * time.Millisecond, ShutdownTimeout: 20 * time.Millisecond})
+		started := make(chan struct{})
+		Handle(w, greet, func(ctx context.Context, g greeting) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		id, err := greet.Enqueue(context.Background(), store, greeting{}, EnqueueOptions{MaxAttempts: 1})
+		require.NoError(t, err)
+		stop := startWorker(t, w)
+		<-started
+		stop()
+
+		job, ok := store.get(id)
+		require.True(t, ok)
+		assert.Equal(t, "pending", job.Status, "interrupted job should be retried")
+		assert.Contains(t, job.LastError, "interrupted by shutdown")
+	})
+}
+
+func TestEnqueueUniqueKey(t *testing.T) {
+	store := newMemStore()
+	first, err := greet.Enqueue(context.Background(), store, greeting{}, EnqueueOptions{UniqueKey: "only"})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, first)
+
+	second, err := greet.Enqueue(context.Background(), store, greeting{}, EnqueueOptions{UniqueKey: "only"})
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, second, "a taken unique key enqueues nothing")
+	assert.Len(t, store.all(), 1)
+}
+
+func TestWorkerRunsRecurringJobs(t *testing.T) {
+	store := newMemStore()
+	tick := Kind[struct{}]("tick")
+	var mu sync.Mutex
+	runs := 0
+	newWorker := func() *Worker {
+		w := NewWorker(store, Options{PollInterval: 10 * time.Millisecond})
+		Every(w, tick, 20*time.Millisecond, func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			runs++
+			return errors.New("failures don't stop it")
+		})
+		return w
+	}
+	// Two workers share the queue but only one job is enqueued.
+	startWorker(t, newWorker())
+	startWorker(t, newWorker())
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return runs >= 3
+	}, time.Second, 10*time.Millisecond)
+
+	jobs := store.all()
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "tick", jobs[0].UniqueKey.String)
+	assert.NotEqual(t, "failed", jobs[0].Status)
+	assert.LessOrEqual(t, jobs[0].Attempts, int32(1), "attempts start over on every run")
+}
+
+func TestBadPayloadIsPermanent(t *testing.T) {
+	w := NewWorker(newMemStore(), Options{})
+	Handle(w, greet, func(ctx context.Context, g greeting) error { return nil })
+	err := w.handlers["greet"](context.Background(), []byte("not json"))
+	assert.True(t, IsPermanent(err))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, Backoff(1))
+	assert.Equal(t, 20*time.Second, Backoff(2))
+	assert.Equal(t, 80*time.Second, Backoff(4))
+	assert.Equal(t, time.Hour, Backoff(30))
+}
@@ -0,0 +1,265 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/google/uuid"
+)
+
+// Defaults for Options.
+const (
+	DefaultConcurrency     = 4
+	DefaultPollInterval    = time.Second
+	DefaultLockDuration    = 5 * time.Minute
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+// Store is the part of the job queue a Worker uses. It is implemented by
+// *database.Queries.
+type Store interface {
+	Enqueuer
+	ClaimJobs(ctx context.Context, arg database.ClaimJobsParams) ([]database.Job, error)
+	DeleteJob(ctx context.Context, id uuid.UUID) error
+	RetryJob(ctx context.Context, arg database.RetryJobParams) error
+	RescheduleJob(ctx context.Context, arg database.RescheduleJobParams) error
+	FailJob(ctx context.Context, arg database.FailJobParams) error
+}
+
+// Options configures a Worker. Zero fields take the defaults above.
+type Options struct {
+	// Concurrency is how many jobs run at once.
+	Concurrency int
+	// PollInterval is how often an idle worker looks for due jobs.
+	PollInterval time.Duration
+	// LockDuration is how long a job may run before it is considered
+	// abandoned and run again by another worker.
+	LockDuration time.Duration
+	// ShutdownTimeout is how long running jobs may take to finish once
+	// the worker is stopped before their contexts are cancelled.
+	ShutdownTimeout time.Duration
+}
+
+// handler runs a job with a JSON payload.
+type handler func(ctx context.Context, payload []byte) error
+
+// Worker runs jobs of the kinds registered with Handle.
+type Worker struct {
+	store    Store
+	opts     Options
+	handlers map[string]handler
+	// recurring holds the interval of each kind registered with Every.
+	recurring map[string]time.Duration
+}
+
+// NewWorker returns a Worker that takes jobs from store.
+func NewWorker(store Store, opts Options) *Worker {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.LockDuration <= 0 {
+		opts.LockDuration = DefaultLockDuration
+	}
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	return &Worker{
+		store:     store,
+		opts:      opts,
+		handlers:  make(map[string]handler),
+		recurring: make(map[string]time.Duration),
+	}
+}
+
+// Handle registers fn to run the jobs of kind k. It must be called before
+// Run. A payload that can't be decoded fails the job without retries.
+func Handle[T any](w *Worker, k Kind[T], fn func(ctx context.Context, payload T) error) {
+	w.handlers[string(k)] = func(ctx context.Context, data []byte) error {
+		var payload T
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return Permanent(fmt.Errorf("decode payload: %w", err))
+		}
+		return fn(ctx, payload)
+	}
+}
+
+// Every registers fn to run every interval as a job of kind k. It must be
+// called before Run. Run enqueues the job with k as its unique key, so
+// workers sharing the queue don't each add one. After every run, whether
+// it succeeded or not, the job is rescheduled interval later.
+func Every(w *Worker, k Kind[struct{}], interval time.Duration, fn func(ctx context.Context) error) {
+	Handle(w, k, func(ctx context.Context, _ struct{}) error {
+		return fn(ctx)
+	})
+	w.recurring[string(k)] = interval
+}
+
+// Run claims and runs jobs until ctx is cancelled. It then stops claiming
+// jobs and waits for the running ones, cancelling them if they take longer
+// than ShutdownTimeout, and returns once they have been recorded.
+func (w *Worker) Run(ctx context.Context) {
+	kinds := make([]string, 0, len(w.handlers))
+	for kind := range w.handlers {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+
+	// Jobs outlive ctx so they can finish during shutdown.
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, w.opts.Concurrency)
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+
+	enqueued := false
+	for ctx.Err() == nil {
+		if !enqueued {
+			enqueued = w.enqueueRecurring(ctx)
+		}
+
+		free := w.opts.Concurrency - len(slots)
+		claimed := 0
+		if free > 0 {
+			jobs, err := w.store.ClaimJobs(ctx, database.ClaimJobsParams{
+				LockedUntil: sql.NullTime{Time: time.Now().Add(w.opts.LockDuration).UTC(), Valid: true},
+				Kinds:       kinds,
+				Limit:       int32(free),
+			})
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Failed to claim jobs: %v", err)
+			}
+			for _, job := range jobs {
+				slots <- struct{}{}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer func() { <-slots }()
+					w.run(jobCtx, job)
+				}()
+			}
+			claimed = len(jobs)
+		}
+		// A full batch means more jobs are probably due.
+		if free > 0 && claimed == free {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(w.opts.ShutdownTimeout):
+		log.Printf("Jobs didn't finish within %s, cancelling them", w.opts.ShutdownTimeout)
+		cancelJobs()
+		<-done
+	}
+}
+
+// enqueueRecurring makes sure every recurring kind has a job queued and
+// reports whether it could.
+func (w *Worker) enqueueRecurring(ctx context.Context) bool {
+	ok := true
+	for kind := range w.recurring {
+		_, err := Kind[struct{}](kind).Enqueue(ctx, w.store, struct{}{}, EnqueueOptions{UniqueKey: kind})
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Failed to enqueue recurring %s job: %v", kind, err)
+			}
+			ok = false
+		}
+	}
+	return ok
+}
+
+// run runs one job and records its outcome.
+func (w *Worker) run(ctx context.Context, job database.Job) {
+	err := w.call(ctx, job)
+
+	// The outcome is recorded even if the job was cancelled by shutdown.
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if err := w.record(recordCtx, ctx, job, err); err != nil {
+		log.Printf("Failed to record %s job %s: %v", job.Kind, job.ID, err)
+	}
+}
+
+// call runs the job's handler, turning a panic into a permanent error.
+func (w *Worker) call(ctx context.Context, job database.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = Permanent(fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	h, ok := w.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for %s jobs", job.Kind))
+	}
+	// A job that outlives its lock may be run again elsewhere, so it is
+	// cancelled when the lock expires.
+	ctx, cancel := context.WithTimeout(ctx, w.opts.LockDuration)
+	defer cancel()
+	return h(ctx, []byte(job.Payload))
+}
+
+// record deletes a job that succeeded, retries one that failed and marks
+// it failed once it is out of attempts. A job interrupted by shutdown is
+// retried right away, and a recurring job is rescheduled for its next run.
+func (w *Worker) record(ctx, jobCtx context.Context, job database.Job, err error) error {
+	if err != nil && jobCtx.Err() != nil {
+		return w.store.RetryJob(ctx, database.RetryJobParams{
+			ID:        job.ID,
+			RunAt:     time.Now().UTC(),
+			LastError: "interrupted by shutdown: " + err.Error(),
+		})
+	}
+
+	if interval, ok := w.recurring[job.Kind]; ok {
+		lastError := ""
+		if err != nil {
+			log.Printf("%s job %s failed: %v", job.Kind, job.ID, err)
+			lastError = err.Error()
+		}
+		return w.store.RescheduleJob(ctx, database.RescheduleJobParams{
+			ID:        job.ID,
+			RunAt:     time.Now().Add(interval).UTC(),
+			LastError: lastError,
+		})
+	}
+
+	if err == nil {
+		return w.store.DeleteJob(ctx, job.ID)
+	}
+
+	if IsPermanent(err) || job.Attempts >= job.MaxAttempts {
+		log.Printf("%s job %s failed after %d attempts: %v", job.Kind, job.ID, job.Attempts, err)
+		return w.store.FailJob(ctx, database.FailJobParams{ID: job.ID, LastError: err.Error()})
+	}
+
+	return w.store.RetryJob(ctx, database.RetryJobParams{
+		ID:        job.ID,
+		RunAt:     time.Now().Add(Backoff(int(job.Attempts))).UTC(),
+		LastError: err.Error(),
+	})
+}
@@ -0,0 +1,253 @@
+// Package media validates uploaded images, strips their metadata and
+// generates thumbnails.
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// ThumbnailSize is the maximum width and height of a thumbnail.
+	ThumbnailSize = 320
+	// MaxPixels limits the decoded size of an image, so that a small file
+	// can't expand into gigabytes of memory.
+	MaxPixels = 40_000_000
+	// MaxGIFFrames and MaxGIFPixels limit animated GIFs, whose frames are
+	// all decoded at once. MaxGIFPixels is the total area of every frame.
+	MaxGIFFrames = 500
+	MaxGIFPixels = MaxPixels
+
+	jpegQuality = 90
+)
+
+var (
+	// ErrUnsupportedType is returned for files that are not JPEG, PNG or
+	// GIF images, whatever their name or declared content type says.
+	ErrUnsupportedType = errors.New("media: unsupported image type")
+	// ErrTooLarge is returned for images with more than MaxPixels pixels
+	// and for GIFs over MaxGIFFrames frames or MaxGIFPixels pixels.
+	ErrTooLarge = errors.New("media: image dimensions too large")
+)
+
+// Image is a processed upload, ready to be stored.
+type Image struct {
+	// ContentType and Ext describe Data, e.g. "image/jpeg" and ".jpg".
+	ContentType string
+	Ext         string
+	Data        []byte
+	Width       int
+	Height      int
+
+	// Thumbnail has the same format as Data, except for GIFs, whose
+	// thumbnail is a still PNG of the first frame.
+	ThumbnailContentType string
+	ThumbnailExt         string
+	Thumbnail            []byte
+}
+
+// Process detects the type of an uploaded image from its contents and
+// re-encodes it. Re-encoding drops EXIF and every other kind of metadata,
+// such as GPS coordinates; the EXIF orientation of JPEGs is applied to the
+// pixels first so photos keep the right way up.
+func Process(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("media: decode: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	switch contentType {
+	case "image/jpeg":
+		return processJPEG(data)
+	case "image/png":
+		return processPNG(data)
+	default:
+		return processGIF(data)
+	}
+}
+
+func processJPEG(data []byte) (*Image, error) {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("media: decode: %w", err)
+	}
+	img = applyOrientation(img, exifOrientation(data))
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("media: encode: %w", err)
+	}
+
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, thumbnail(img), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("media: encode thumbnail: %w", err)
+	}
+
+	bounds := img.Bounds()
+	return &Image{
+		ContentType:          "image/jpeg",
+		Ext:                  ".jpg",
+		Data:                 out.Bytes(),
+		Width:                bounds.Dx(),
+		Height:               bounds.Dy(),
+		ThumbnailContentType: "image/jpeg",
+		ThumbnailExt:         ".jpg",
+		Thumbnail:            thumb.Bytes(),
+	}, nil
+}
+
+func processPNG(data []byte) (*Image, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("media: decode: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		return nil, fmt.Errorf("media: encode: %w", err)
+	}
+
+	var thumb bytes.Buffer
+	if err := png.Encode(&thumb, thumbnail(img)); err != nil {
+		return nil, fmt.Errorf("media: encode thumbnail: %w", err)
+	}
+
+	bounds := img.Bounds()
+	return &Image{
+		ContentType:          "image/png",
+		Ext:                  ".png",
+		Data:                 out.Bytes(),
+		Width:                bounds.Dx(),
+		Height:               bounds.Dy(),
+		ThumbnailContentType: "image/png",
+		ThumbnailExt:         ".png",
+		Thumbnail:            thumb.Bytes(),
+	}, nil
+}
+
+// processGIF keeps every frame so animations still play. The GIF encoder
+// writes no comment or application extensions other than looping.
+func processGIF(data []byte) (*Image, error) {
+	frames, pixels := gifFrameStats(data)
+	if frames > MaxGIFFrames || pixels > MaxGIFPixels {
+		return nil, ErrTooLarge
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("media: decode: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := gif.EncodeAll(&out, g); err != nil {
+		return nil, fmt.Errorf("media: encode: %w", err)
+	}
+
+	var thumb bytes.Buffer
+	if err := png.Encode(&thumb, thumbnail(g.Image[0])); err != nil {
+		return nil, fmt.Errorf("media: encode thumbnail: %w", err)
+	}
+
+	return &Image{
+		ContentType:          "image/gif",
+		Ext:                  ".gif",
+		Data:                 out.Bytes(),
+		Width:                g.Config.Width,
+		Height:               g.Config.Height,
+		ThumbnailContentType: "image/png",
+		ThumbnailExt:         ".png",
+		Thumbnail:            thumb.Bytes(),
+	}, nil
+}
+
+// gifFrameStats walks the blocks of a GIF without decoding any pixels
+// and returns its number of frames and their total area. It stops at the
+// first malformed block, leaving gif.DecodeAll to report the error.
+func gifFrameStats(data []byte) (frames, pixels int) {
+	// Signature, version and the logical screen descriptor.
+	const headerLen = 13
+	if len(data) < headerLen {
+		return 0, 0
+	}
+	i := headerLen
+	if flags := data[10]; flags&0x80 != 0 {
+		i += 3 << (flags&0x07 + 1) // global color table
+	}
+
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension: label, then data sub-blocks
+			i = skipGIFSubBlocks(data, i+2)
+		case 0x2C: // image descriptor
+			if i+10 > len(data) {
+				return frames, pixels
+			}
+			w := int(binary.LittleEndian.Uint16(data[i+5:]))
+			h := int(binary.LittleEndian.Uint16(data[i+7:]))
+			frames++
+			pixels += w * h
+
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1) // local color table
+			}
+			// Skip the LZW minimum code size, then the image data.
+			i = skipGIFSubBlocks(data, i+1)
+		default: // trailer
+			return frames, pixels
+		}
+	}
+	return frames, pixels
+}
+
+// skipGIFSubBlocks returns the index just past the sequence of data
+// sub-blocks starting at i.
+func skipGIFSubBlocks(data []byte, i int) int {
+	for i < len(data) {
+		n := int(data[i])
+		i++
+		if n == 0 {
+			return i
+		}
+		i += n
+	}
+	return len(data)
+}
+
+// thumbnail scales img down to fit in a ThumbnailSize square, keeping
+// its aspect ratio. Smaller images are copied at their own size.
+func thumbnail(img image.Image) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > ThumbnailSize || h > ThumbnailSize {
+		if w >= h {
+			w, h = ThumbnailSize, max(1, h*ThumbnailSize/w)
+		} else {
+			w, h = max(1, w*ThumbnailSize/h), ThumbnailSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,167 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	return img
+}
+
+// withOrientation inserts an APP1 EXIF segment carrying orientation right
+// after the SOI marker of a JPEG.
+func withOrientation(jpg []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1) // one IFD entry
+	tiff = binary.BigEndian.AppendUint16(tiff, orientationTag)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1) // count
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)       // value padding
+	tiff = append(tiff, 0, 0, 0, 0) // no next IFD
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+func TestProcessJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, testImage(800, 400), nil))
+	data := withOrientation(buf.Bytes(), 6)
+	require.Equal(t, 6, exifOrientation(data))
+
+	img, err := Process(data)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", img.ContentType)
+	assert.Equal(t, 400, img.Width, "orientation 6 swaps width and height")
+	assert.Equal(t, 800, img.Height)
+	assert.Equal(t, 1, exifOrientation(img.Data), "EXIF is stripped")
+	assert.NotContains(t, string(img.Data), "Exif")
+
+	thumb, err := jpeg.DecodeConfig(bytes.NewReader(img.Thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, 160, thumb.Width)
+	assert.Equal(t, 320, thumb.Height)
+}
+
+func TestProcessPNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, testImage(100, 50)))
+
+	img, err := Process(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", img.ContentType)
+	assert.Equal(t, ".png", img.Ext)
+	assert.Equal(t, 100, img.Width)
+	assert.Equal(t, 50, img.Height)
+
+	thumb, err := png.DecodeConfig(bytes.NewReader(img.Thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, 100, thumb.Width, "small images are not upscaled")
+}
+
+func TestProcessGIF(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	g := &gif.GIF{
+		Image: []*image.Paletted{
+			image.NewPaletted(image.Rect(0, 0, 640, 480), palette),
+			image.NewPaletted(image.Rect(0, 0, 640, 480), palette),
+		},
+		Delay: []int{10, 10},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&buf, g))
+
+	img, err := Process(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "image/gif", img.ContentType)
+	out, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	require.NoError(t, err)
+	assert.Len(t, out.Image, 2, "animation frames are kept")
+	assert.Equal(t, "image/png", img.ThumbnailContentType)
+}
+
+func encodeGIF(t *testing.T, frame *image.Paletted, n int) []byte {
+	t.Helper()
+	g := &gif.GIF{}
+	for range n {
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&buf, g))
+	return buf.Bytes()
+}
+
+func TestProcessGIFLimits(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+
+	small := image.NewPaletted(image.Rect(0, 0, 2, 2), palette)
+	data := encodeGIF(t, small, MaxGIFFrames)
+	frames, pixels := gifFrameStats(data)
+	assert.Equal(t, MaxGIFFrames, frames)
+	assert.Equal(t, 4*MaxGIFFrames, pixels)
+	_, err := Process(data)
+	assert.NoError(t, err)
+
+	_, err = Process(encodeGIF(t, small, MaxGIFFrames+1))
+	assert.ErrorIs(t, err, ErrTooLarge, "too many frames")
+
+	// Each frame is within MaxPixels, but together they decode to more
+	// than MaxGIFPixels.
+	large := image.NewPaletted(image.Rect(0, 0, 5000, 1000), palette)
+	_, err = Process(encodeGIF(t, large, MaxGIFPixels/5_000_000+1))
+	assert.ErrorIs(t, err, ErrTooLarge, "too many pixels")
+}
+
+func TestProcessRejects(t *testing.T) {
+	_, err := Process([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+
+	_, err = Process([]byte("GIF89a not really"))
+	assert.Error(t, err)
+}
+
+func TestApplyOrientation(t *testing.T) {
+	src := testImage(3, 2)
+	cases := map[int]struct {
+		w, h int
+		x, y int // where the source pixel (0, 0) ends up
+	}{
+		1: {3, 2, 0, 0},
+		2: {3, 2, 2, 0},
+		3: {3, 2, 2, 1},
+		4: {3, 2, 0, 1},
+		5: {2, 3, 0, 0},
+		6: {2, 3, 1, 0},
+		7: {2, 3, 1, 2},
+		8: {2, 3, 0, 2},
+	}
+	for orientation, want := range cases {
+		out := applyOrientation(src, orientation)
+		assert.Equal(t, image.Rect(0, 0, want.w, want.h), out.Bounds(), "orientation %d", orientation)
+		assert.Equal(t, color.RGBAModel.Convert(src.At(0, 0)), color.RGBAModel.Convert(out.At(want.x, want.y)), "orientation %d", orientation)
+	}
+}
@@ -0,0 +1,106 @@
+package stream
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgresChannel is the NOTIFY channel PostgresBroker uses by default.
+const PostgresChannel = "chirp_events"
+
+// listenerPingInterval is how long PostgresBroker waits for a
+// notification before checking that its connection is still alive.
+const listenerPingInterval = 90 * time.Second
+
+// PostgresBroker is a Broker that uses Postgres LISTEN/NOTIFY, so that
+// every server instance connected to the same database sees every event.
+// Notification payloads are limited to 8000 bytes by Postgres.
+type PostgresBroker struct {
+	db       *sql.DB
+	channel  string
+	listener *pq.Listener
+	events   chan Event
+	done     chan struct{}
+}
+
+// NewPostgresBroker listens on channel using a dedicated connection to
+// dbURL and publishes through db.
+func NewPostgresBroker(db *sql.DB, dbURL, channel string) (*PostgresBroker, error) {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Stream listener: %v", err)
+		}
+	})
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("stream: listen %s: %w", channel, err)
+	}
+
+	b := &PostgresBroker{
+		db:       db,
+		channel:  channel,
+		listener: listener,
+		events:   make(chan Event, subscriberBuffer),
+		done:     make(chan struct{}),
+	}
+	go b.receive()
+	return b, nil
+}
+
+func (b *PostgresBroker) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("stream: marshal event: %w", err)
+	}
+	if _, err := b.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", b.channel, string(payload)); err != nil {
+		return fmt.Errorf("stream: notify: %w", err)
+	}
+	return nil
+}
+
+func (b *PostgresBroker) Events() <-chan Event {
+	return b.events
+}
+
+func (b *PostgresBroker) Close() error {
+	close(b.done)
+	return b.listener.Close()
+}
+
+func (b *PostgresBroker) receive() {
+	defer close(b.events)
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case n := <-b.listener.Notify:
+			// A nil notification means the connection was re-established;
+			// anything sent in between is lost and clients will resume
+			// from the history of the events that did arrive.
+			if n == nil {
+				continue
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(n.Extra), &event); err != nil {
+				log.Printf("Stream listener: invalid event: %v", err)
+				continue
+			}
+			select {
+			case b.events <- event:
+			case <-b.done:
+				return
+			}
+		case <-time.After(listenerPingInterval):
+			if err := b.listener.Ping(); err != nil {
+				log.Printf("Stream listener: ping: %v", err)
+			}
+		}
+	}
+}
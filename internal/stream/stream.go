@@ -0,0 +1,215 @@
+// Package stream fans out real-time chirp events to connected clients.
+//
+// Events are published through a Broker, which delivers them to the Hub
+// of every server instance. Each Hub keeps a short history so clients
+// that reconnect can resume from the last event they saw.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Event types.
+const (
+	EventChirpCreated = "chirp.created"
+	EventChirpDeleted = "chirp.deleted"
+)
+
+const (
+	// DefaultHistorySize is the number of past events a Hub keeps for
+	// clients that resume with Last-Event-ID.
+	DefaultHistorySize = 1024
+	// subscriberBuffer is how many events may queue up for a subscriber
+	// before it is considered too slow and dropped.
+	subscriberBuffer = 64
+)
+
+// Event is a message delivered to stream subscribers.
+type Event struct {
+	// ID is unique and increases with the time the event was published.
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// AuthorID is the author of the chirp the event is about, so that
+	// subscribers can skip authors they blocked or muted.
+	AuthorID uuid.UUID       `json:"author_id"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// Broker carries events between server instances. Implementations must be
+// safe for concurrent use.
+type Broker interface {
+	// Publish sends event to every Hub, including the one on this instance.
+	Publish(ctx context.Context, event Event) error
+	// Events returns the channel on which published events are delivered.
+	// It is closed when the broker is closed.
+	Events() <-chan Event
+	Close() error
+}
+
+// Subscription receives the events published after it was created.
+type Subscription struct {
+	// C is closed when the subscription is cancelled or when the
+	// subscriber fell too far behind. A dropped client should reconnect
+	// with the ID of the last event it received.
+	C <-chan Event
+
+	ch chan Event
+}
+
+// Hub delivers events from a Broker to local subscribers.
+type Hub struct {
+	broker      Broker
+	historySize int
+
+	mu      sync.Mutex
+	history []Event
+	subs    map[*Subscription]struct{}
+}
+
+// NewHub returns a Hub that receives events from broker. Call Run to start
+// delivering them.
+func NewHub(broker Broker, historySize int) *Hub {
+	return &Hub{
+		broker:      broker,
+		historySize: historySize,
+		subs:        make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish marshals data and publishes it as an event of the given type
+// about a chirp written by authorID.
+func (h *Hub) Publish(ctx context.Context, eventType string, authorID uuid.UUID, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("stream: marshal %s: %w", eventType, err)
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("stream: %w", err)
+	}
+	return h.broker.Publish(ctx, Event{
+		ID:       id.String(),
+		Type:     eventType,
+		AuthorID: authorID,
+		Data:     raw,
+	})
+}
+
+// Run delivers events from the broker to subscribers until ctx is
+// cancelled or the broker's event channel is closed.
+func (h *Hub) Run(ctx context.Context) {
+	events := h.broker.Events()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			h.deliver(event)
+		}
+	}
+}
+
+func (h *Hub) deliver(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.history = append(h.history, event)
+	if len(h.history) > h.historySize {
+		h.history = h.history[len(h.history)-h.historySize:]
+	}
+
+	for sub := range h.subs {
+		select {
+		case sub.ch <- event:
+		default:
+			delete(h.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber. If lastEventID is not empty, the
+// events published after it are returned so the subscriber can catch up;
+// ok is false if lastEventID is no longer in the history, in which case
+// the subscriber has missed events and should reload.
+func (h *Hub) Subscribe(lastEventID string) (sub *Subscription, missed []Event, ok bool) {
+	ch := make(chan Event, subscriberBuffer)
+	sub = &Subscription{C: ch, ch: ch}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.subs[sub] = struct{}{}
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+	for i := len(h.history) - 1; i >= 0; i-- {
+		if h.history[i].ID == lastEventID {
+			return sub, append([]Event(nil), h.history[i+1:]...), true
+		}
+	}
+	return sub, nil, false
+}
+
+// Unsubscribe removes sub and closes its channel. It is safe to call more
+// than once and after the Hub dropped the subscriber.
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// LocalBroker is a Broker for a single server instance.
+type LocalBroker struct {
+	events chan Event
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewLocalBroker returns a LocalBroker.
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{events: make(chan Event, subscriberBuffer)}
+}
+
+func (b *LocalBroker) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return fmt.Errorf("stream: broker closed")
+	}
+	select {
+	case b.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *LocalBroker) Events() <-chan Event {
+	return b.events
+}
+
+func (b *LocalBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.events)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startHub(t *testing.T, historySize int) *Hub {
+	t.Helper()
+	broker := NewLocalBroker()
+	hub := NewHub(broker, historySize)
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		broker.Close()
+	})
+	return hub
+}
+
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event, ok := <-sub.C:
+		require.True(t, ok, "subscription closed")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func TestHubDelivers(t *testing.T) {
+	hub := startHub(t, DefaultHistorySize)
+	sub, missed, ok := hub.Subscribe("")
+	require.True(t, ok)
+	assert.Empty(t, missed)
+	defer hub.Unsubscribe(sub)
+
+	author := uuid.New()
+	require.NoError(t, hub.Publish(context.Background(), EventChirpCreated, author, map[string]string{"body": "hi"}))
+
+	event := receive(t, sub)
+	assert.NotEmpty(t, event.ID)
+	assert.Equal(t, EventChirpCreated, event.Type)
+	assert.Equal(t, author, event.AuthorID)
+	assert.JSONEq(t, `{"body":"hi"}`, string(event.Data))
+}
+
+func TestHubResume(t *testing.T) {
+	hub := startHub(t, 2)
+	sub, _, _ := hub.Subscribe("")
+	defer hub.Unsubscribe(sub)
+
+	ctx := context.Background()
+	var ids []string
+	for i := 0; i < 3; i++ {
+		require.NoError(t, hub.Publish(ctx, EventChirpCreated, uuid.New(), i))
+		ids = append(ids, receive(t, sub).ID)
+	}
+
+	resumed, missed, ok := hub.Subscribe(ids[1])
+	defer hub.Unsubscribe(resumed)
+	require.True(t, ok)
+	require.Len(t, missed, 1)
+	assert.Equal(t, ids[2], missed[0].ID)
+
+	// The first event has fallen out of the history.
+	gone, missed, ok := hub.Subscribe(ids[0])
+	defer hub.Unsubscribe(gone)
+	assert.False(t, ok)
+	assert.Empty(t, missed)
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	hub := startHub(t, DefaultHistorySize)
+	sub, _, _ := hub.Subscribe("")
+
+	ctx := context.Background()
+	for i := 0; i <= subscriberBuffer; i++ {
+		require.NoError(t, hub.Publish(ctx, EventChirpDeleted, uuid.New(), i))
+	}
+	// Wait for the hub to deliver everything before reading, so the
+	// subscriber really falls behind.
+	require.Eventually(t, func() bool {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		return len(hub.history) == subscriberBuffer+1
+	}, time.Second, time.Millisecond)
+
+	received := 0
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-sub.C:
+			if !ok {
+				assert.Equal(t, subscriberBuffer, received)
+				hub.Unsubscribe(sub) // must not panic after being dropped
+				return
+			}
+			received++
+		case <-timeout:
+			t.Fatal("slow subscriber was not dropped")
+		}
+	}
+}
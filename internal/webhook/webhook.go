@@ -0,0 +1,165 @@
+// Package webhook signs and sends webhook deliveries to integrators.
+//
+// Every request carries the event type, a delivery ID, a Unix timestamp
+// and an HMAC-SHA256 signature of "<timestamp>.<body>" keyed with the
+// webhook's secret, so receivers can check that a delivery is authentic
+// and recent.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Request headers set on every delivery.
+const (
+	EventHeader     = "X-Chirpy-Event"
+	DeliveryHeader  = "X-Chirpy-Delivery"
+	TimestampHeader = "X-Chirpy-Timestamp"
+	SignatureHeader = "X-Chirpy-Signature"
+)
+
+const (
+	// DefaultTimeout bounds a single delivery attempt.
+	DefaultTimeout = 10 * time.Second
+
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+	// maxResponseBody is how much of a failed response is kept as the
+	// delivery's error message.
+	maxResponseBody = 512
+)
+
+// ErrPrivateAddress is returned when a webhook URL resolves to a loopback,
+// private or link-local address, which a Sender refuses to call unless it
+// was created to allow private networks.
+var ErrPrivateAddress = errors.New("webhook: address is not public")
+
+// NewSecret returns a random signing secret for a new webhook.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("webhook: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header value for body sent at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns how long to wait before retrying a delivery that has
+// failed attempts times: 30s, 1m, 2m and so on, capped at 6h.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := baseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
+
+// Delivery is one event sent to one webhook.
+type Delivery struct {
+	ID        string
+	URL       string
+	Secret    string
+	EventType string
+	Body      []byte
+}
+
+// Sender sends deliveries over HTTP.
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender returns a Sender. Unless allowPrivateNetworks is set, it
+// refuses to connect to non-public addresses, so webhooks can't be used
+// to reach services inside our network.
+func NewSender(allowPrivateNetworks bool) *Sender {
+	dialer := &net.Dialer{Timeout: DefaultTimeout}
+	if !allowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublic(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &Sender{client: &http.Client{
+		Transport: transport,
+		Timeout:   DefaultTimeout,
+		// A redirect could point at an address we'd refuse to call
+		// directly, and integrators should register the final URL.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+func isPublic(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast())
+}
+
+// Send posts the delivery and returns the response status code. Any
+// status outside 2xx is returned together with an error; a zero status
+// means no response was received.
+func (s *Sender) Send(ctx context.Context, d Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return 0, fmt.Errorf("webhook: %w", err)
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, d.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(d.Secret, now, d.Body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+		return resp.StatusCode, fmt.Errorf("webhook: unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	sig := Sign("secret", ts, []byte(`{"a":1}`))
+	assert.Equal(t, sig, Sign("secret", ts, []byte(`{"a":1}`)))
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, sig)
+	assert.NotEqual(t, sig, Sign("other", ts, []byte(`{"a":1}`)))
+	assert.NotEqual(t, sig, Sign("secret", ts.Add(time.Second), []byte(`{"a":1}`)))
+	assert.NotEqual(t, sig, Sign("secret", ts, []byte(`{"a":2}`)))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 4*time.Minute, Backoff(4))
+	assert.Equal(t, 6*time.Hour, Backoff(20))
+	assert.Equal(t, 30*time.Second, Backoff(0))
+}
+
+func TestSend(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	sender := NewSender(true)
+	status, err := sender.Send(context.Background(), Delivery{
+		ID:        "d1",
+		URL:       server.URL,
+		Secret:    "secret",
+		EventType: "chirp.created",
+		Body:      []byte(`{"id":"1"}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	assert.Equal(t, `{"id":"1"}`, string(body))
+	assert.Equal(t, "chirp.created", got.Header.Get(EventHeader))
+	assert.Equal(t, "d1", got.Header.Get(DeliveryHeader))
+	unix, err := strconv.ParseInt(got.Header.Get(TimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, Sign("secret", time.Unix(unix, 0), body), got.Header.Get(SignatureHeader))
+}
+
+func TestSendFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	status, err := NewSender(true).Send(context.Background(), Delivery{URL: server.URL, Body: []byte("{}")})
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.ErrorContains(t, err, "nope")
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	status, err := NewSender(false).Send(context.Background(), Delivery{URL: server.URL, Body: []byte("{}")})
+	assert.Zero(t, status)
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+}
@@ -7,8 +7,11 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/joho/godotenv"
 
+	"context"
 	"database/sql"
 	"os"
+	"os/signal"
+	"syscall"
 	"fmt"
 	"net/http"
 	"sync/atomic"
@@ -16,21 +19,53 @@ import (
 	"html/template"
     "encoding/json"
 	"regexp"
+	"strconv"
 	"time"
-	"strings"
 	"errors"
 	
 	"github.com/BabichevDima/goServer/internal/database"
 	"github.com/BabichevDima/goServer/internal/auth"
+	"github.com/BabichevDima/goServer/internal/blobstore"
+	"github.com/BabichevDima/goServer/internal/jobs"
+	"github.com/BabichevDima/goServer/internal/stream"
+	"github.com/BabichevDima/goServer/internal/webhook"
 	"github.com/google/uuid"
 )
 
+// serverShutdownTimeout is how long in-flight requests may take to finish
+// once the server is asked to stop.
+const serverShutdownTimeout = 15 * time.Second
+
+// jobConcurrency is how many background jobs run at once. Webhook
+// deliveries spend most of their time waiting on other servers, so it is
+// well above jobs.DefaultConcurrency.
+const jobConcurrency = 20
+
 // apiConfig holds application configuration and shared state.
 // The fileserverHits field tracks the number of requests made to the fileserver.
 type apiConfig struct {
 	jwtSecret		string
 	fileserverHits	atomic.Int32
+	db				*sql.DB
 	DB				*database.Queries 
+	// deletionGracePeriod is how long a deleted account can still be
+	// restored by logging in. Zero means accounts are deleted immediately.
+	deletionGracePeriod	time.Duration
+	// revokedTokenRetention is how long revoked refresh tokens are kept
+	// before the janitor deletes them.
+	revokedTokenRetention	time.Duration
+	tokenPurges	tokenPurgeStats
+	// maxChirpLength is the maximum number of characters in a chirp body.
+	maxChirpLength	int
+	// blobs stores uploaded images.
+	blobs	blobstore.BlobStore
+	// hub delivers chirp events to clients of the real-time stream.
+	hub	*stream.Hub
+	// webhooks sends deliveries to users' webhooks.
+	webhooks	*webhook.Sender
+	// shutdown is closed when the server starts shutting down, to end
+	// long-lived streams that would otherwise hold the shutdown up.
+	shutdown	chan struct{}
 }
 
 type User struct {
@@ -38,16 +73,30 @@ type User struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	Email        string    `json:"email"`
+	Handle       string    `json:"handle"`
+	DisplayName  string    `json:"display_name"`
+	Bio          string    `json:"bio"`
+	AvatarURL    string    `json:"avatar_url"`
 	Token        string    `json:"token,omitempty"`
 	RefreshToken string    `json:"refresh_token,omitempty"`
 }
 
 type Chirp struct {
-    ID        uuid.UUID `json:"id"`
-    CreatedAt time.Time `json:"created_at"`
-    UpdatedAt time.Time `json:"updated_at"`
-    Body      string    `json:"body"`
-    UserID    uuid.UUID `json:"user_id"`
+    ID        uuid.UUID  `json:"id"`
+    CreatedAt time.Time  `json:"created_at"`
+    UpdatedAt time.Time  `json:"updated_at"`
+    Body      string     `json:"body"`
+    UserID    uuid.UUID  `json:"user_id"`
+    InReplyTo *uuid.UUID `json:"in_reply_to,omitempty"`
+    LikeCount int64      `json:"like_count"`
+    LikedByMe bool       `json:"liked_by_me"`
+    Hidden    bool       `json:"hidden,omitempty"`
+    Media     []MediaAttachment `json:"media,omitempty"`
+    PublishAt *time.Time `json:"publish_at,omitempty"`
+
+    Kind              string     `json:"kind"`
+    ReferencedChirpID *uuid.UUID `json:"referenced_chirp_id,omitempty"`
+    ReferencedChirp   *Chirp     `json:"referenced_chirp,omitempty"`
 }
 
 // middlewareMetricsInc creates a middleware that increments the hit counter
@@ -59,22 +108,38 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	})
 }
 
-// handlerMetrics writes the current hit count in the format "Hits: N".
+// handlerMetrics writes the hit count and the refresh token janitor's
+// counters as an HTML page.
 // It responds with Content-Type: text/html and HTTP 200 status.
 func (cfg *apiConfig) handlerMetrics(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
-	hits := cfg.fileserverHits.Load()
+	data := struct {
+		Hits               int32
+		TokenPurgeRuns     int64
+		TokensPurged       int64
+		LastTokensPurged   int64
+		LastTokenPurgeTime string
+	}{
+		Hits:             cfg.fileserverHits.Load(),
+		TokenPurgeRuns:   cfg.tokenPurges.runs.Load(),
+		TokensPurged:     cfg.tokenPurges.purged.Load(),
+		LastTokensPurged: cfg.tokenPurges.lastPurged.Load(),
+	}
+	if unix := cfg.tokenPurges.lastRunUnix.Load(); unix != 0 {
+		data.LastTokenPurgeTime = time.Unix(unix, 0).UTC().Format(time.RFC3339)
+	}
 	tmpl := template.Must(template.New("metrics").Parse(`
 	<html>
 	<body>
 		<h1>Welcome, Chirpy Admin</h1>
-		<p>Chirpy has been visited {{.}} times!</p>
+		<p>Chirpy has been visited {{.Hits}} times!</p>
+		<p>Refresh tokens purged: {{.TokensPurged}} in {{.TokenPurgeRuns}} runs{{if .LastTokenPurgeTime}}, {{.LastTokensPurged}} in the last run at {{.LastTokenPurgeTime}}{{end}}</p>
 	</body>
 	</html>
 	`))
     
-    tmpl.Execute(w, hits)
+    tmpl.Execute(w, data)
 
 }
 
@@ -91,17 +156,18 @@ func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
 // from the environment variable DB_URL (loaded via .env file).
 //
 // It returns:
-//   - *database.Queries: A prepared queries object for database operations
+//   - *sql.DB: The connection pool, used to build queries and run transactions
+//   - string: The JWT signing secret from JWT_SECRET
 //   - error: Any error that occurred during connection (e.g., environment loading failure,
 //     invalid connection URL, or connection failure)
 //
 // Example usage:
-//   queries, err := connectToDB()
+//   db, jwtSecret, err := connectToDB()
 //   if err != nil {
 //       log.Fatal(err)
 //   }
-//   defer queries.Close()
-func connectToBD() (*database.Queries, string, error) {
+//   defer db.Close()
+func connectToBD() (*sql.DB, string, error) {
 	godotenv.Load()
 	dbURL := os.Getenv("DB_URL")
 	jwtSecret := os.Getenv("JWT_SECRET")
@@ -113,9 +179,22 @@ func connectToBD() (*database.Queries, string, error) {
 		return nil, "", fmt.Errorf("failed to connect to db: %w", err)
 	}
 
-	dbQueries := database.New(db)
+	return db, jwtSecret, nil
+}
+
+// withTx runs fn inside a database transaction. The transaction is
+// committed if fn returns nil and rolled back otherwise.
+func (cfg *apiConfig) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	return dbQueries, jwtSecret, nil
+	if err := fn(cfg.DB.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // main initializes and starts the HTTP server on localhost:8080.
@@ -126,8 +205,11 @@ func connectToBD() (*database.Queries, string, error) {
 // - /api/metrics (hit counter metrics)
 // - /api/reset (hit counter reset)
 func main() {
-	dbQueries, jwtSecret, err := connectToBD()
-	// fmt.Println("dbQueries = ", dbQueries)
+	// SIGINT or SIGTERM stops the server gracefully; a second one kills it.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, jwtSecret, err := connectToBD()
 
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
@@ -136,30 +218,153 @@ func main() {
 	fmt.Println("Server started on localhost:8080")
 	mux := http.NewServeMux()
 	apiCfg := &apiConfig{
-		DB: dbQueries,
+		db: db,
+		DB: database.New(db),
 		jwtSecret: jwtSecret,
+		shutdown: make(chan struct{}),
 	}
 
 	if apiCfg.jwtSecret == "" {
 		log.Fatal("JWT_SECRET environment variable is not set")
 	}
 
+	if gracePeriod := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"); gracePeriod != "" {
+		apiCfg.deletionGracePeriod, err = time.ParseDuration(gracePeriod)
+		if err != nil || apiCfg.deletionGracePeriod < 0 {
+			log.Fatalf("Invalid ACCOUNT_DELETION_GRACE_PERIOD %q", gracePeriod)
+		}
+	}
+	apiCfg.revokedTokenRetention = defaultRevokedTokenRetention
+	if retention := os.Getenv("REFRESH_TOKEN_RETENTION"); retention != "" {
+		apiCfg.revokedTokenRetention, err = time.ParseDuration(retention)
+		if err != nil || apiCfg.revokedTokenRetention < 0 {
+			log.Fatalf("Invalid REFRESH_TOKEN_RETENTION %q", retention)
+		}
+	}
+	apiCfg.maxChirpLength = defaultMaxChirpLength
+	if maxLength := os.Getenv("CHIRP_MAX_LENGTH"); maxLength != "" {
+		apiCfg.maxChirpLength, err = strconv.Atoi(maxLength)
+		if err != nil || apiCfg.maxChirpLength < 1 {
+			log.Fatalf("Invalid CHIRP_MAX_LENGTH %q", maxLength)
+		}
+	}
+
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = defaultMediaDir
+	}
+	apiCfg.blobs, err = blobstore.NewFS(mediaDir)
+	if err != nil {
+		log.Fatalf("Failed to open media storage: %v", err)
+	}
+
+	// STREAM_BROKER=postgres fans stream events out to every instance
+	// sharing the database; by default events stay in this process.
+	var broker stream.Broker
+	switch os.Getenv("STREAM_BROKER") {
+	case "", "local":
+		broker = stream.NewLocalBroker()
+	case "postgres":
+		broker, err = stream.NewPostgresBroker(db, os.Getenv("DB_URL"), stream.PostgresChannel)
+		if err != nil {
+			log.Fatalf("Failed to start stream broker: %v", err)
+		}
+	default:
+		log.Fatalf("Invalid STREAM_BROKER %q", os.Getenv("STREAM_BROKER"))
+	}
+	apiCfg.hub = stream.NewHub(broker, stream.DefaultHistorySize)
+	go apiCfg.hub.Run(context.Background())
+
+	// Webhooks may only call public addresses unless
+	// WEBHOOK_ALLOW_PRIVATE_NETWORKS=true, which is meant for development.
+	apiCfg.webhooks = webhook.NewSender(os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true")
+
+	worker := jobs.NewWorker(apiCfg.DB, jobs.Options{Concurrency: jobConcurrency})
+	jobs.Handle(worker, publishChirpJob, apiCfg.publishScheduledChirp)
+	jobs.Handle(worker, deliverWebhookJob, apiCfg.deliverWebhook)
+	jobs.Every(worker, purgeRefreshTokensJob, tokenPurgeInterval, apiCfg.runRefreshTokenPurge)
+	jobs.Every(worker, purgeMediaJob, mediaPurgeInterval, apiCfg.runMediaPurge)
+	if apiCfg.deletionGracePeriod > 0 {
+		jobs.Every(worker, purgeDeletedUsersJob, userPurgeInterval, apiCfg.runDeletedUserPurge)
+	}
+	workerDone := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(workerDone)
+	}()
+
 	// Fileservers
 	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app/", http.FileServer(http.Dir("./")))))
 	mux.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.Dir("./"))))
+	mux.Handle("GET /assets/media/{key}", http.HandlerFunc(apiCfg.handlerServeMedia))
 
 	// API endpoints
 	mux.Handle("GET /api/healthz", middlewareLog(http.HandlerFunc(healthzHandler)))
 	mux.Handle("POST /api/users", middlewareLog(http.HandlerFunc(apiCfg.handlerCreateUser)))
 	mux.Handle("PUT /api/users", middlewareLog(http.HandlerFunc(apiCfg.handlerUpdateUser)))
+	mux.Handle("PATCH /api/users", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerPatchUser)))
+	mux.Handle("DELETE /api/users", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerDeleteUser)))
+	mux.Handle("GET /api/users/export", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerExportUser)))
+	mux.Handle("GET /api/users/{handle}", middlewareLog(http.HandlerFunc(apiCfg.handlerGetUserProfile)))
+	mux.Handle("PUT /api/users/{handle}/follow", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerFollowUser)))
+	mux.Handle("DELETE /api/users/{handle}/follow", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerUnfollowUser)))
+	mux.Handle("GET /api/users/{handle}/followers", middlewareLog(http.HandlerFunc(apiCfg.handlerGetFollowers)))
+	mux.Handle("GET /api/users/{handle}/following", middlewareLog(http.HandlerFunc(apiCfg.handlerGetFollowing)))
+	mux.Handle("GET /api/users/me/blocks", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerGetBlocks)))
+	mux.Handle("GET /api/users/me/mutes", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerGetMutes)))
+	mux.Handle("PUT /api/users/{handle}/block", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerBlockUser)))
+	mux.Handle("DELETE /api/users/{handle}/block", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerUnblockUser)))
+	mux.Handle("PUT /api/users/{handle}/mute", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerMuteUser)))
+	mux.Handle("DELETE /api/users/{handle}/mute", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerUnmuteUser)))
+	mux.Handle("GET /api/users/me/mentions", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerGetMentions)))
+	mux.Handle("GET /api/hashtags/trending", middlewareLog(http.HandlerFunc(apiCfg.handlerGetTrendingHashtags)))
+	mux.Handle("GET /api/hashtags/{tag}/chirps", middlewareLog(http.HandlerFunc(apiCfg.handlerGetHashtagChirps)))
+	mux.Handle("GET /api/search/chirps", middlewareLog(http.HandlerFunc(apiCfg.handlerSearchChirps)))
+	mux.Handle("GET /api/timeline", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerGetTimeline)))
 	mux.Handle("POST /api/login", middlewareLog(http.HandlerFunc(apiCfg.handlerLogin)))
 	mux.Handle("POST /api/refresh", middlewareLog(http.HandlerFunc(apiCfg.handlerRefresh)))
 	mux.Handle("POST /api/revoke", middlewareLog(http.HandlerFunc(apiCfg.handlerRevoke)))
-	mux.Handle("POST /api/chirps", middlewareLog(http.HandlerFunc(apiCfg.handlerCreateChirp)))
+	mux.Handle("POST /api/media", middlewareLog(apiCfg.middlewareAuthScope(scopeChirpsWrite, apiCfg.handlerUploadMedia)))
+	mux.Handle("POST /api/chirps", middlewareLog(apiCfg.middlewareAuthScope(scopeChirpsWrite, apiCfg.handlerCreateChirp)))
 	mux.Handle("GET /api/chirps", middlewareLog(http.HandlerFunc(apiCfg.handlerGetChirps)))
+	mux.Handle("GET /api/chirps/stream", middlewareLog(http.HandlerFunc(apiCfg.handlerChirpStream)))
+	mux.Handle("GET /api/ws", middlewareLog(http.HandlerFunc(apiCfg.handlerWebSocket)))
+	mux.Handle("GET /api/chirps/scheduled", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerGetScheduledChirps)))
+	mux.Handle("DELETE /api/chirps/{chirpID}/schedule", middlewareLog(apiCfg.middlewareAuthScope(scopeChirpsDelete, apiCfg.handlerCancelScheduledChirp)))
 	mux.Handle("GET /api/chirps/{chirpID}", middlewareLog(http.HandlerFunc(apiCfg.handlerGetChirp)))
-	mux.Handle("DELETE /api/chirps/{chirpID}", middlewareLog(http.HandlerFunc(apiCfg.handlerDeleteChirp)))
-
+	mux.Handle("DELETE /api/chirps/{chirpID}", middlewareLog(apiCfg.middlewareAuthScope(scopeChirpsDelete, apiCfg.handlerDeleteChirp)))
+	mux.Handle("PATCH /api/chirps/{chirpID}", middlewareLog(apiCfg.middlewareAuthScope(scopeChirpsWrite, apiCfg.handlerUpdateChirp)))
+	mux.Handle("GET /api/chirps/{chirpID}/history", middlewareLog(http.HandlerFunc(apiCfg.handlerGetChirpHistory)))
+	mux.Handle("GET /api/chirps/{chirpID}/replies", middlewareLog(http.HandlerFunc(apiCfg.handlerGetChirpReplies)))
+	mux.Handle("GET /api/chirps/{chirpID}/thread", middlewareLog(http.HandlerFunc(apiCfg.handlerGetChirpThread)))
+	mux.Handle("PUT /api/chirps/{chirpID}/like", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerLikeChirp)))
+	mux.Handle("DELETE /api/chirps/{chirpID}/like", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerUnlikeChirp)))
+	mux.Handle("PUT /api/chirps/{chirpID}/bookmark", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerBookmarkChirp)))
+	mux.Handle("DELETE /api/chirps/{chirpID}/bookmark", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerUnbookmarkChirp)))
+	mux.Handle("POST /api/chirps/{chirpID}/report", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerReportChirp)))
+	mux.Handle("GET /api/notifications", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerGetNotifications)))
+	mux.Handle("POST /api/notifications/read", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerMarkNotificationsRead)))
+	mux.Handle("POST /api/webhooks", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerCreateWebhook)))
+	mux.Handle("GET /api/webhooks", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerGetWebhooks)))
+	mux.Handle("DELETE /api/webhooks/{webhookID}", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerDeleteWebhook)))
+	mux.Handle("GET /api/webhooks/{webhookID}/deliveries", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerGetWebhookDeliveries)))
+	mux.Handle("POST /api/api-keys", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerCreateAPIKey)))
+	mux.Handle("GET /api/api-keys", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerGetAPIKeys)))
+	mux.Handle("DELETE /api/api-keys/{keyID}", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerRevokeAPIKey)))
+	mux.Handle("POST /api/oauth/clients", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerCreateOAuthClient)))
+	mux.Handle("GET /api/oauth/clients", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerGetOAuthClients)))
+	mux.Handle("DELETE /api/oauth/clients/{clientID}", middlewareLog(apiCfg.middlewareAuthScope(scopeSession, apiCfg.handlerDeleteOAuthClient)))
+	mux.Handle("GET /oauth/authorize", middlewareLog(http.HandlerFunc(apiCfg.handlerOAuthAuthorize)))
+	mux.Handle("POST /oauth/authorize", middlewareLog(http.HandlerFunc(apiCfg.handlerOAuthConsent)))
+	mux.Handle("POST /oauth/token", middlewareLog(http.HandlerFunc(apiCfg.handlerOAuthToken)))
+	mux.Handle("GET /api/bookmarks", middlewareLog(apiCfg.middlewareAuth(apiCfg.handlerGetBookmarks)))
+
+	mux.Handle("GET /admin/reports", middlewareLog(apiCfg.middlewareModerator(apiCfg.handlerGetReports)))
+	mux.Handle("POST /admin/reports/{reportID}/resolve", middlewareLog(apiCfg.middlewareModerator(apiCfg.handlerResolveReport)))
+	mux.Handle("POST /admin/reports/{reportID}/dismiss", middlewareLog(apiCfg.middlewareModerator(apiCfg.handlerDismissReport)))
+	mux.Handle("PUT /admin/chirps/{chirpID}/hidden", middlewareLog(apiCfg.middlewareModerator(apiCfg.handlerHideChirp)))
+	mux.Handle("DELETE /admin/chirps/{chirpID}/hidden", middlewareLog(apiCfg.middlewareModerator(apiCfg.handlerUnhideChirp)))
+	mux.Handle("POST /admin/refresh-tokens/purge", middlewareLog(apiCfg.middlewareAdmin(apiCfg.handlerPurgeRefreshTokens)))
 	mux.Handle("POST /admin/reset", middlewareLog(http.HandlerFunc(apiCfg.handlerReset)))
 	mux.Handle("GET /admin/metrics", middlewareLog(http.HandlerFunc(apiCfg.handlerMetrics)))
 
@@ -167,12 +372,25 @@ func main() {
 		Addr:    ":8080",
 		Handler: mux,
 	}
+	server.RegisterOnShutdown(func() { close(apiCfg.shutdown) })
 
-	err = server.ListenAndServe()
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
 
-	if err != nil {
-		panic(err)
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server didn't shut down cleanly: %v", err)
 	}
+	// Running jobs get their own grace period to finish.
+	<-workerDone
 }
 
 // middlewareLog creates a middleware that logs the HTTP method and path
@@ -184,6 +402,33 @@ func middlewareLog(next http.Handler) http.Handler {
 	})
 }
 
+// authedHandler is an HTTP handler that also receives the ID of the
+// user authenticated by middlewareAuth.
+type authedHandler func(http.ResponseWriter, *http.Request, uuid.UUID)
+
+// middlewareAuth creates a middleware that validates the bearer access token
+// or API key and passes the authenticated user ID to the next handler.
+// It responds with 401 if the credential is missing or invalid, and with
+// 403 if an API key lacks the scope the request method needs.
+func (cfg *apiConfig) middlewareAuth(next authedHandler) http.Handler {
+	return cfg.middlewareAuthScope("", next)
+}
+
+// optionalUserID returns the ID of the user the request is authenticated
+// as, or uuid.Nil for anonymous requests, invalid credentials and API keys
+// without the read scope. It is used by public endpoints whose response
+// depends on the viewer.
+func (cfg *apiConfig) optionalUserID(r *http.Request) uuid.UUID {
+	if r.Header.Get("Authorization") == "" {
+		return uuid.Nil
+	}
+	cred, apiErr := cfg.authenticate(r)
+	if apiErr != nil || !cred.allows(scopeRead) {
+		return uuid.Nil
+	}
+	return cred.UserID
+}
+
 // healthzHandler responds to health check requests.
 // It always returns "OK" with Content-Type: text/plain and HTTP 200 status.
 func healthzHandler (w http.ResponseWriter, r *http.Request) {
@@ -196,6 +441,7 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 	type parameters struct {
 		Email string `json:"email"`
 		Password string `json:"password"`
+		Handle string `json:"handle"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -203,34 +449,41 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 	err := decoder.Decode(&params)
 
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
 		return
 	}
 
+	handle := sql.NullString{}
+	if params.Handle != "" {
+		normalized, ok := normalizeHandle(params.Handle)
+		if !ok {
+			respondWithError(w, http.StatusBadRequest, ErrCodeValidation, handleRulesMessage)
+			return
+		}
+		handle = sql.NullString{String: normalized, Valid: true}
+	}
+
 	if params.Email == "" {
-		respondWithError(w, http.StatusBadRequest, "Email is required")
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Email is required")
 		return
 	}
 	if params.Password == "" {
-		respondWithError(w, http.StatusBadRequest, "Password is required")
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Password is required")
 		return
 	}
 
 	hashPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Email HashPassword")
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to hash password")
 		return
 	}
 	user, err := cfg.DB.CreateUser(r.Context(), database.CreateUserParams{
 		Email:			params.Email,
 		HashedPassword:	hashPassword,
+		Handle:			handle,
 	})
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate key") {
-			respondWithError(w, http.StatusConflict, "Email already exists")
-			return
-		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to create user")
+		respondWithAPIError(w, userConflictError(err, "Failed to create user"))
 		return
 	}
 
@@ -239,19 +492,24 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
 		Email:     user.Email,
+		Handle:    user.Handle.String,
 	})
 }
 
 func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request) {
 	accessToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Authentication required")
+		respondWithError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Authentication required")
 		return
 	}
 
 	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid access Token")
+		respondWithError(w, http.StatusUnauthorized, ErrCodeInvalidToken, "Invalid access token")
+		return
+	}
+	if apiErr := cfg.requireActiveUser(r.Context(), userID); apiErr != nil {
+		respondWithAPIError(w, apiErr)
 		return
 	}
 
@@ -265,24 +523,24 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request)
 	err = decoder.Decode(&params)
 
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
 		return
 	}
 
     if params.Email == "" || params.Password == "" {
-        respondWithError(w, http.StatusBadRequest, "Email and password are required")
+        respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Email and password are required")
         return
     }
 
 	hashPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Failed to hash password")
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to hash password")
 		return
 	}
 
 	currentUser, err := cfg.DB.GetUserByID(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get user data")
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to get user data")
 		return
 	}
 
@@ -293,7 +551,7 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request)
 			ID:            userID,
 		})
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to update user")
+			respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to update user")
 			return
 		}
 		respondWithJSON(w, http.StatusOK, User{
@@ -311,11 +569,7 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request)
 		ID:             userID,
 	})
 	if err != nil {
-		if strings.Contains(err.Error(), "unique constraint") {
-			respondWithError(w, http.StatusConflict, "Email already in use")
-			return
-		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to update user")
+		respondWithAPIError(w, userConflictError(err, "Failed to update user"))
 		return
 	}
 
@@ -337,35 +591,47 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	params := parameters{}
 	err := decoder.Decode(&params)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
 		return
 	}
 
 	if params.Email == "" || params.Password == "" {
-		respondWithError(w, http.StatusBadRequest, "Email and password are required")
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Email and password are required")
 		return
 	}
 
 	user, err := cfg.DB.GetUserByEmail(r.Context(), params.Email)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get user")
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Incorrect email or password")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to get user")
 		return
 	}
 
 	if err := auth.CheckPasswordHash(params.Password, user.HashedPassword); err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+		respondWithError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Incorrect email or password")
 		return
 	}
 
+	// Logging in during the deletion grace period cancels the deletion.
+	if user.DeletedAt.Valid {
+		if err := cfg.DB.RestoreUser(r.Context(), user.ID); err != nil {
+			respondWithAPIError(w, dbError(err, "Failed to restore user"))
+			return
+		}
+	}
+
 	accessToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Hour)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create access Token")
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to create access token")
 		return
 	}
 
 	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create Refresh Token")
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to create refresh token")
 		return
 	}
 
@@ -376,7 +642,7 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		ExpiresAt: expiresAt,
 	})
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to save refresh token")
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to save refresh token")
 		return
 	}
 
@@ -385,6 +651,10 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		CreatedAt:    user.CreatedAt,
 		UpdatedAt:    user.UpdatedAt,
 		Email:        user.Email,
+		Handle:       user.Handle.String,
+		DisplayName:  user.DisplayName,
+		Bio:          user.Bio,
+		AvatarURL:    user.AvatarUrl,
 		Token:        accessToken,
 		RefreshToken: refreshToken,
 	})
@@ -393,25 +663,23 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 	refreshToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid authorization header")
+		respondWithError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Invalid authorization header")
 		return
 	}
 
 	user, err := cfg.DB.GetUserFromRefreshToken(r.Context(), refreshToken)
-	fmt.Println(user.ID)
-	fmt.Println(user.Email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			respondWithError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+			respondWithError(w, http.StatusUnauthorized, ErrCodeInvalidToken, "Invalid or expired refresh token")
 		} else {
-			respondWithError(w, http.StatusInternalServerError, "Failed to validate refresh token")
+			respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to validate refresh token")
 		}
 		return
 	}
 
 	accessToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Hour)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to generate access token")
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to generate access token")
 		return
 	}
 
@@ -425,91 +693,210 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Authentication required")
+		respondWithError(w, http.StatusUnauthorized, ErrCodeUnauthorized, "Authentication required")
 		return
 	}
 
 	if err := cfg.DB.RevokeRefreshToken(r.Context(), token); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to Refresh Token")
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to revoke refresh token")
 		return
 	}
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
 
-func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request) {
+func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body      string     `json:"body"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
+		QuoteOf   *uuid.UUID `json:"quote_of"`
+		RechirpOf *uuid.UUID `json:"rechirp_of"`
+		MediaIDs  []uuid.UUID `json:"media_ids"`
+		PublishAt *time.Time  `json:"publish_at"`
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Authentication required")
+		respondWithError(w, http.StatusBadRequest, ErrCodeInvalidPayload, "Invalid request payload")
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid token")
+	if params.QuoteOf != nil && params.RechirpOf != nil {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "A chirp can't be both a quote and a rechirp")
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	params := parameters{}
-	err = decoder.Decode(&params)
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Something went wrong")
+	if len(params.MediaIDs) > maxChirpMedia {
+		respondWithAPIError(w, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    ErrCodeValidation,
+			Message: "Too many media attachments",
+			Details: map[string]any{"limit": maxChirpMedia},
+		})
 		return
 	}
 
-	if params.Body == "" {
-		respondWithError(w, http.StatusBadRequest, "Chirp is empty")
-		return
-	} else if len(params.Body) > 140 {
-		respondWithError(w, http.StatusBadRequest, "Chirp is too long")
-		return
+	newChirp := database.CreateChirpParams{
+		UserID: userID,
+		Kind:   chirpKindChirp,
 	}
 
-	cleanedBody := replacer(params.Body)
+	if params.PublishAt != nil {
+		if apiErr := validatePublishAt(*params.PublishAt, time.Now()); apiErr != nil {
+			respondWithAPIError(w, apiErr)
+			return
+		}
+		newChirp.PublishAt = sql.NullTime{Time: params.PublishAt.UTC(), Valid: true}
+	}
 
-	chirp, err := cfg.DB.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleanedBody,
-		UserID: userID,
+	if params.RechirpOf != nil {
+		if params.Body != "" || params.InReplyTo != nil || len(params.MediaIDs) > 0 {
+			respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "A rechirp can't have a body, media or be a reply")
+			return
+		}
+
+		referencedID, apiErr := cfg.resolveChirpReference(r.Context(), userID, *params.RechirpOf)
+		if apiErr != nil {
+			respondWithAPIError(w, apiErr)
+			return
+		}
+		newChirp.Kind = chirpKindRechirp
+		newChirp.ReferencedChirpID = uuid.NullUUID{UUID: referencedID, Valid: true}
+	} else {
+		// A chirp with images doesn't need any text.
+		if params.Body != "" || len(params.MediaIDs) == 0 {
+			cleanedBody, apiErr := cfg.cleanChirpBody(params.Body)
+			if apiErr != nil {
+				respondWithAPIError(w, apiErr)
+				return
+			}
+			newChirp.Body = cleanedBody
+		}
+
+		if params.QuoteOf != nil {
+			referencedID, apiErr := cfg.resolveChirpReference(r.Context(), userID, *params.QuoteOf)
+			if apiErr != nil {
+				respondWithAPIError(w, apiErr)
+				return
+			}
+			newChirp.Kind = chirpKindQuote
+			newChirp.ReferencedChirpID = uuid.NullUUID{UUID: referencedID, Valid: true}
+		}
+
+		if params.InReplyTo != nil {
+			parent, err := cfg.DB.GetChirp(r.Context(), database.GetChirpParams{
+				ID:       *params.InReplyTo,
+				ViewerID: userID,
+			})
+			if err != nil {
+				respondWithAPIError(w, dbError(err, "Chirp being replied to not found"))
+				return
+			}
+			if parent.Status != chirpStatusPublished {
+				respondWithError(w, http.StatusNotFound, ErrCodeNotFound, "Chirp being replied to not found")
+				return
+			}
+			if apiErr := cfg.checkCanInteract(r.Context(), userID, parent.UserID); apiErr != nil {
+				respondWithAPIError(w, apiErr)
+				return
+			}
+			newChirp.ParentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		}
+	}
+
+	var chirp database.Chirp
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		chirp, err = q.CreateChirp(r.Context(), newChirp)
+		if err != nil {
+			return err
+		}
+		if len(params.MediaIDs) > 0 {
+			attached, err := q.AttachMedia(r.Context(), database.AttachMediaParams{
+				ChirpID:  uuid.NullUUID{UUID: chirp.ID, Valid: true},
+				MediaIds: params.MediaIDs,
+				UserID:   userID,
+			})
+			if err != nil {
+				return err
+			}
+			if len(attached) != len(params.MediaIDs) {
+				return errMediaNotAttachable
+			}
+		}
+		// Scheduled chirps are indexed and notified about when they are
+		// published, so they don't show up in hashtags, mentions or
+		// notifications early.
+		if chirp.Status != chirpStatusPublished {
+			return enqueueChirpPublish(r.Context(), q, chirp)
+		}
+		if err := indexChirpText(r.Context(), q, chirp.ID, chirp.Body); err != nil {
+			return err
+		}
+		return notifyChirpPublished(r.Context(), q, chirp)
 	})
+	if errors.Is(err, errMediaNotAttachable) {
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Media not found, not yours or already attached to a chirp")
+		return
+	}
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate key") {
-			respondWithError(w, http.StatusConflict, "Chirp already exists")
+		if newChirp.Kind == chirpKindRechirp && isUniqueViolation(err) {
+			respondWithAPIError(w, dbError(err, "You already rechirped this chirp"))
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to create chirp")
+		respondWithAPIError(w, dbError(err, "Failed to create chirp"))
 		return
 	}
-	respondWithJSON(w, http.StatusCreated, Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	})
+
+	chirps := []Chirp{chirpFromDB(chirp)}
+	if err := cfg.hydrateChirps(r.Context(), chirps, userID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get referenced chirp"))
+		return
+	}
+
+	if chirp.Status == chirpStatusPublished {
+		cfg.publishChirpCreated(r.Context(), chirps[0])
+	}
+
+	respondWithJSON(w, http.StatusCreated, chirps[0])
 }
 
+// handlerGetChirps returns all chirps oldest first.
+// The optional "author" query parameter takes a comma-separated list of
+// user handles and limits the result to chirps written by those users.
 func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
-	dbChirps, err := cfg.DB.GetChirps(r.Context())
+	var dbChirps []database.Chirp
+	var err error
+
+	viewerID := cfg.optionalUserID(r)
+	if authorParam := r.URL.Query().Get("author"); authorParam != "" {
+		handles, ok := parseHandleList(authorParam)
+		if !ok {
+			respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid author handle")
+			return
+		}
+		dbChirps, err = cfg.DB.GetChirpsByAuthorHandles(r.Context(), database.GetChirpsByAuthorHandlesParams{
+			Handles:  handles,
+			ViewerID: viewerID,
+		})
+	} else {
+		dbChirps, err = cfg.DB.GetChirps(r.Context(), viewerID)
+	}
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Failed to get chirps")
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to get chirps")
 		return
 	}
 
 	chirps := make([]Chirp, len(dbChirps))
-
 	for i, dbChirp := range dbChirps {
-		chirps[i] = Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID,
-		}
+		chirps[i] = chirpFromDB(dbChirp)
+	}
+
+	if err := cfg.hydrateChirps(r.Context(), chirps, viewerID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp details"))
+		return
 	}
 
 	respondWithJSON(w, http.StatusOK, chirps)
@@ -520,43 +907,45 @@ func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, r *http.Request) {
 
 	chirpID, err := uuid.Parse(chirpIDStr)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid chirpID format")
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
 		return
 	}
 
-	dbChirp, err := cfg.DB.GetChirp(r.Context(), chirpID)
+	viewerID := cfg.optionalUserID(r)
+	dbChirp, err := cfg.DB.GetChirp(r.Context(), database.GetChirpParams{ID: chirpID, ViewerID: viewerID})
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Failed to get chirp")
+		respondWithAPIError(w, dbError(err, "Failed to get chirp"))
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
-	})
-}
-
-func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
-	accessToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Authentication required")
+	if !chirpVisibleTo(dbChirp, viewerID) {
+		respondWithError(w, http.StatusNotFound, ErrCodeNotFound, "Chirp not found")
 		return
 	}
 
-	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusForbidden, "not allow")
+	chirps := []Chirp{chirpFromDB(dbChirp)}
+	if err := cfg.hydrateChirps(r.Context(), chirps, viewerID); err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to get chirp details"))
 		return
 	}
 
+	respondWithJSON(w, http.StatusOK, chirps[0])
+}
+
+func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
 	chirpIDStr := r.PathValue("chirpID")
 	chirpID, err := uuid.Parse(chirpIDStr)
 
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid chirpID format")
+		respondWithError(w, http.StatusBadRequest, ErrCodeValidation, "Invalid chirpID format")
+		return
+	}
+
+	// Attached media rows are deleted with the chirp, so remember their
+	// blobs first.
+	attachedMedia, err := cfg.DB.GetMediaForChirps(r.Context(), []uuid.UUID{chirpID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to delete chirp")
 		return
 	}
 
@@ -565,23 +954,29 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 		UserID: userID,
 	})
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete chirp")
+		respondWithError(w, http.StatusInternalServerError, ErrCodeInternal, "Failed to delete chirp")
 		return
 	}
 
 	if result == 0 {
-		respondWithError(w, http.StatusForbidden, "You can't delete this chirp")
+		respondWithError(w, http.StatusForbidden, ErrCodeForbidden, "You can't delete this chirp")
 		return
 	}
 
+	for _, m := range attachedMedia {
+		cfg.deleteBlobs(r.Context(), m.StorageKey, m.ThumbnailKey)
+	}
+
+	cfg.publishChirpDeleted(r.Context(), chirpID, userID)
+
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
 
-// respondWithError is a helper function to send JSON error responses.
-// It sets the appropriate content type and HTTP status code,
-// then encodes the error message as JSON.
-func respondWithError(w http.ResponseWriter, code int, message string) {
-    respondWithJSON(w, code, map[string]string{"error": message})
+// respondWithError is a helper function to send error responses.
+// It wraps the HTTP status, the stable error code and the message
+// in an APIError and renders it as problem+json.
+func respondWithError(w http.ResponseWriter, status int, code, message string) {
+	respondWithAPIError(w, newAPIError(status, code, message))
 }
 
 // respondWithJSON is a helper function to send JSON responses.
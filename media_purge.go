@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/BabichevDima/goServer/internal/jobs"
+)
+
+const (
+	// unattachedMediaTTL is how long an upload may wait to be attached to
+	// a chirp before it is deleted.
+	unattachedMediaTTL = 24 * time.Hour
+	// mediaPurgeInterval is how often purgeMediaJob runs.
+	mediaPurgeInterval = time.Hour
+	// mediaPurgeBatchSize is how many uploads are deleted per statement.
+	mediaPurgeBatchSize = 100
+)
+
+// purgeMediaJob deletes uploads that were never attached to a chirp
+// within unattachedMediaTTL, along with their files. It recurs every
+// mediaPurgeInterval.
+const purgeMediaJob = jobs.Kind[struct{}]("media.purge")
+
+// runMediaPurge runs purgeMediaJob.
+func (cfg *apiConfig) runMediaPurge(ctx context.Context) error {
+	purged, err := cfg.purgeUnattachedMedia(ctx, time.Now().Add(-unattachedMediaTTL))
+	if purged > 0 {
+		log.Printf("Purged %d unattached uploads", purged)
+	}
+	return err
+}
+
+// purgeUnattachedMedia deletes unattached uploads created before cutoff
+// in batches until none are left and returns how many it deleted. Rows
+// are deleted before their files, so a file that can't be deleted is
+// only left orphaned, and logged by deleteBlobs.
+func (cfg *apiConfig) purgeUnattachedMedia(ctx context.Context, cutoff time.Time) (int, error) {
+	total := 0
+	for {
+		purged, err := cfg.DB.PurgeUnattachedMedia(ctx, database.PurgeUnattachedMediaParams{
+			Cutoff: cutoff,
+			Limit:  mediaPurgeBatchSize,
+		})
+		if err != nil {
+			return total, err
+		}
+		for _, m := range purged {
+			cfg.deleteBlobs(ctx, m.StorageKey, m.ThumbnailKey)
+		}
+		total += len(purged)
+		if len(purged) < mediaPurgeBatchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageCursor marks a position in a list ordered by (created_at, id).
+// It is handed to clients as an opaque string and sent back in the
+// "cursor" query parameter to fetch the next page.
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// latestCursor sorts after every row. Lists ordered newest first use it
+// in place of the zero cursor when no cursor was given.
+var latestCursor = pageCursor{
+	CreatedAt: time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC),
+	ID:        uuid.Max,
+}
+
+// orLatest returns latestCursor if c is the zero cursor and c otherwise.
+func (c pageCursor) orLatest() pageCursor {
+	if c == (pageCursor{}) {
+		return latestCursor
+	}
+	return c
+}
+
+// String encodes the cursor as an opaque URL-safe token.
+func (c pageCursor) String() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// parseCursor decodes a token produced by pageCursor.String.
+func parseCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, errors.New("invalid cursor encoding")
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return pageCursor{}, errors.New("invalid cursor timestamp")
+	}
+
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return pageCursor{}, errors.New("invalid cursor id")
+	}
+
+	return pageCursor{CreatedAt: t, ID: parsedID}, nil
+}
+
+// parsePage reads the "cursor" and "limit" query parameters.
+// A missing cursor yields the zero cursor, which sorts before every row.
+func parsePage(r *http.Request) (pageCursor, int32, *APIError) {
+	limit, apiErr := parseLimit(r)
+	if apiErr != nil {
+		return pageCursor{}, 0, apiErr
+	}
+
+	var cursor pageCursor
+	if cursorStr := r.URL.Query().Get("cursor"); cursorStr != "" {
+		var err error
+		cursor, err = parseCursor(cursorStr)
+		if err != nil {
+			return pageCursor{}, 0, &APIError{Status: http.StatusBadRequest, Code: ErrCodeValidation, Message: "Invalid cursor", Err: err}
+		}
+	}
+
+	return cursor, limit, nil
+}
+
+// parseLimit reads the "limit" query parameter, defaulting to defaultPageSize.
+func parseLimit(r *http.Request) (int32, *APIError) {
+	limit := defaultPageSize
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 1 || n > maxPageSize {
+			return 0, &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    ErrCodeValidation,
+				Message: "Invalid limit",
+				Details: map[string]any{"min": 1, "max": maxPageSize},
+			}
+		}
+		limit = n
+	}
+	return int32(limit), nil
+}
+
+// Page is a page of chirps with the cursor for the next page.
+// NextCursor is empty when there are no more results.
+type Page struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// newPage builds a Page from the chirps of one query. A full page means
+// there may be more rows, so the last chirp becomes the next cursor.
+func newPage(chirps []Chirp, limit int32) Page {
+	page := Page{Chirps: chirps}
+	if len(chirps) > 0 && len(chirps) == int(limit) {
+		last := chirps[len(chirps)-1]
+		page.NextCursor = pageCursor{CreatedAt: last.CreatedAt, ID: last.ID}.String()
+	}
+	return page
+}
+
+// ProfilePage is a page of user profiles with the cursor for the next page.
+type ProfilePage struct {
+	Users      []PublicProfile `json:"users"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/BabichevDima/goServer/internal/jobs"
+	"github.com/BabichevDima/goServer/internal/stream"
+	"github.com/google/uuid"
+)
+
+// userPurgeInterval is how often purgeDeletedUsersJob looks for accounts
+// whose deletion grace period is over.
+const userPurgeInterval = time.Hour
+
+// purgeDeletedUsersJob permanently removes soft-deleted users once their
+// grace period has passed. Their chirps, media rows and refresh tokens
+// are removed by ON DELETE CASCADE. It recurs every userPurgeInterval.
+const purgeDeletedUsersJob = jobs.Kind[struct{}]("users.purge")
+
+// runDeletedUserPurge runs purgeDeletedUsersJob.
+func (cfg *apiConfig) runDeletedUserPurge(ctx context.Context) error {
+	purged, err := cfg.purgeDeletedUsers(ctx, time.Now().Add(-cfg.deletionGracePeriod))
+	if purged > 0 {
+		log.Printf("Purged %d deleted users", purged)
+	}
+	return err
+}
+
+// purgeDeletedUsers purges every user deleted before cutoff, one at a
+// time so that each account's leftovers can be cleaned up after it.
+func (cfg *apiConfig) purgeDeletedUsers(ctx context.Context, cutoff time.Time) (int, error) {
+	userIDs, err := cfg.DB.GetPurgeableUserIDs(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, userID := range userIDs {
+		leftovers, err := cfg.getAccountLeftovers(ctx, userID)
+		if err != nil {
+			return purged, err
+		}
+
+		n, err := cfg.DB.PurgeDeletedUser(ctx, database.PurgeDeletedUserParams{
+			ID:     userID,
+			Cutoff: cutoff,
+		})
+		if err != nil {
+			return purged, err
+		}
+		if n == 0 {
+			// Restored since GetPurgeableUserIDs ran.
+			continue
+		}
+
+		cfg.cleanUpDeletedAccount(ctx, userID, leftovers)
+		purged++
+	}
+	return purged, nil
+}
+
+// accountLeftovers is what deleting a user leaves behind outside the
+// database: media files in blob storage and chirps that stream clients
+// may still be showing.
+type accountLeftovers struct {
+	blobKeys []string
+	chirpIDs []uuid.UUID
+}
+
+// getAccountLeftovers reads a user's leftovers. It must run before the
+// user is deleted, since the cascade removes the rows it reads.
+func (cfg *apiConfig) getAccountLeftovers(ctx context.Context, userID uuid.UUID) (accountLeftovers, error) {
+	media, err := cfg.DB.GetMediaByUser(ctx, userID)
+	if err != nil {
+		return accountLeftovers{}, err
+	}
+
+	chirpIDs, err := cfg.DB.GetPublishedChirpIDsByUser(ctx, userID)
+	if err != nil {
+		return accountLeftovers{}, err
+	}
+
+	leftovers := accountLeftovers{chirpIDs: chirpIDs}
+	for _, m := range media {
+		leftovers.blobKeys = append(leftovers.blobKeys, m.StorageKey, m.ThumbnailKey)
+	}
+	return leftovers, nil
+}
+
+// cleanUpDeletedAccount deletes a deleted user's media files and tells
+// stream clients that their chirps are gone. No chirp.deleted webhooks
+// are sent: webhooks belong to the deleted user and went with the account.
+func (cfg *apiConfig) cleanUpDeletedAccount(ctx context.Context, userID uuid.UUID, leftovers accountLeftovers) {
+	cfg.deleteBlobs(ctx, leftovers.blobKeys...)
+
+	for _, chirpID := range leftovers.chirpIDs {
+		data := map[string]uuid.UUID{"id": chirpID}
+		if err := cfg.hub.Publish(ctx, stream.EventChirpDeleted, userID, data); err != nil {
+			log.Printf("Failed to publish deletion of chirp %s: %v", chirpID, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/BabichevDima/goServer/internal/jobs"
+	"github.com/google/uuid"
+)
+
+// publishChirpJob publishes a scheduled chirp. It is enqueued with the
+// chirp and runs at its publish_at.
+const publishChirpJob = jobs.Kind[publishChirpPayload]("chirp.publish")
+
+type publishChirpPayload struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+}
+
+// enqueueChirpPublish schedules the publication of a scheduled chirp as
+// part of the transaction that creates it.
+func enqueueChirpPublish(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	_, err := publishChirpJob.Enqueue(ctx, q, publishChirpPayload{ChirpID: chirp.ID}, jobs.EnqueueOptions{
+		RunAt: chirp.PublishAt.Time,
+	})
+	return err
+}
+
+// publishScheduledChirp publishes a scheduled chirp, indexes its hashtags
+// and mentions and sends its notifications, which are left out while a
+// chirp is scheduled, and announces it on the real-time stream. A chirp
+// that was cancelled or already published is skipped.
+func (cfg *apiConfig) publishScheduledChirp(ctx context.Context, payload publishChirpPayload) error {
+	var published database.Chirp
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		var err error
+		published, err = q.PublishScheduledChirp(ctx, payload.ChirpID)
+		if err != nil {
+			return err
+		}
+		if err := indexChirpText(ctx, q, published.ID, published.Body); err != nil {
+			return err
+		}
+		return notifyChirpPublished(ctx, q, published)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	chirps := []Chirp{chirpFromDB(published)}
+	if err := cfg.hydrateChirps(ctx, chirps, uuid.Nil); err != nil {
+		log.Printf("Failed to load published chirp %s for the stream: %v", published.ID, err)
+		return nil
+	}
+	cfg.publishChirpCreated(ctx, chirps[0])
+	return nil
+}
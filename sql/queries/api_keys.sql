@@ -0,0 +1,38 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (user_id, name, prefix, hashed_key, scopes)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetAPIKeys :many
+SELECT * FROM api_keys
+WHERE user_id = $1
+AND revoked_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: CountAPIKeys :one
+SELECT COUNT(*) FROM api_keys
+WHERE user_id = $1
+AND revoked_at IS NULL;
+
+-- name: GetAPIKeyByHash :one
+-- Returns an active key whose owner's account isn't deleted.
+SELECT api_keys.* FROM api_keys
+JOIN users ON users.id = api_keys.user_id
+WHERE api_keys.hashed_key = $1
+AND api_keys.revoked_at IS NULL
+AND users.deleted_at IS NULL;
+
+-- name: TouchAPIKey :exec
+-- Records that a key was used. Updates are throttled to one a minute so
+-- busy keys don't write on every request.
+UPDATE api_keys
+SET last_used_at = NOW()
+WHERE id = $1
+AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+
+-- name: RevokeAPIKey :execrows
+UPDATE api_keys
+SET revoked_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND revoked_at IS NULL;
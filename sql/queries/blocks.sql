@@ -0,0 +1,70 @@
+-- name: BlockUser :exec
+INSERT INTO blocks (blocker_id, blocked_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = $1
+AND blocked_id = $2;
+
+-- name: GetBlockedUsers :many
+SELECT
+    users.id,
+    users.created_at,
+    users.handle,
+    users.display_name,
+    users.bio,
+    users.avatar_url,
+    blocks.created_at AS blocked_at
+FROM blocks
+JOIN users ON users.id = blocks.blocked_id
+WHERE blocks.blocker_id = sqlc.arg('user_id')
+AND users.deleted_at IS NULL
+AND (blocks.created_at, users.id) < (sqlc.arg('before_created_at')::timestamp, sqlc.arg('before_id')::uuid)
+ORDER BY blocks.created_at DESC, users.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: HasBlockBetween :one
+-- Reports whether either user has blocked the other.
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = sqlc.arg('user_id') AND blocked_id = sqlc.arg('other_id'))
+    OR (blocker_id = sqlc.arg('other_id') AND blocked_id = sqlc.arg('user_id'))
+);
+
+-- name: MuteUser :exec
+INSERT INTO mutes (muter_id, muted_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM mutes
+WHERE muter_id = $1
+AND muted_id = $2;
+
+-- name: GetMutedUsers :many
+SELECT
+    users.id,
+    users.created_at,
+    users.handle,
+    users.display_name,
+    users.bio,
+    users.avatar_url,
+    mutes.created_at AS muted_at
+FROM mutes
+JOIN users ON users.id = mutes.muted_id
+WHERE mutes.muter_id = sqlc.arg('user_id')
+AND users.deleted_at IS NULL
+AND (mutes.created_at, users.id) < (sqlc.arg('before_created_at')::timestamp, sqlc.arg('before_id')::uuid)
+ORDER BY mutes.created_at DESC, users.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetExcludedAuthorIDs :many
+-- Returns the users whose chirps are left out of the viewer's listings:
+-- everyone the viewer blocked or muted and everyone who blocked the viewer.
+SELECT blocked_id AS user_id FROM blocks WHERE blocker_id = $1
+UNION
+SELECT blocker_id FROM blocks WHERE blocked_id = $1
+UNION
+SELECT muted_id FROM mutes WHERE muter_id = $1;
@@ -0,0 +1,43 @@
+-- name: BookmarkChirp :exec
+INSERT INTO bookmarks (user_id, chirp_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnbookmarkChirp :exec
+DELETE FROM bookmarks
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: GetBookmarkedChirps :many
+SELECT
+    chirps.id,
+    chirps.created_at,
+    chirps.updated_at,
+    chirps.body,
+    chirps.user_id,
+    chirps.parent_id,
+    chirps.kind,
+    chirps.referenced_chirp_id,
+    chirps.hidden_at,
+    chirps.status,
+    chirps.publish_at,
+    bookmarks.created_at AS bookmarked_at
+FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+JOIN users ON users.id = chirps.user_id
+WHERE bookmarks.user_id = sqlc.arg('user_id')
+AND users.deleted_at IS NULL
+AND (bookmarks.created_at, chirps.id) < (sqlc.arg('before_created_at')::timestamp, sqlc.arg('before_id')::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg('user_id') AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('user_id'))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg('user_id') AND mutes.muted_id = chirps.user_id
+)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.arg('user_id'))
+AND chirps.status = 'published'
+ORDER BY bookmarks.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
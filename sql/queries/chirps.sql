@@ -0,0 +1,119 @@
+-- name: UpdateChirpBody :one
+WITH previous AS (
+    INSERT INTO chirp_revisions (chirp_id, body, created_at)
+    SELECT id, body, updated_at FROM chirps
+    WHERE chirps.id = $1
+    AND chirps.user_id = $2
+)
+UPDATE chirps
+SET
+    body = $3,
+    updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+RETURNING *;
+
+-- name: GetChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY replaced_at DESC;
+
+-- name: GetChirpReplies :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.parent_id = sqlc.arg('parent_id')
+AND users.deleted_at IS NULL
+AND (chirps.created_at, chirps.id) > (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('viewer_id'))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg('viewer_id') AND mutes.muted_id = chirps.user_id
+)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.arg('viewer_id'))
+AND chirps.status = 'published'
+ORDER BY chirps.created_at, chirps.id
+LIMIT sqlc.arg('limit');
+
+-- name: GetChirpThread :many
+-- Walks up the parent chain and down the reply tree of a chirp.
+-- depth is negative for ancestors, 0 for the chirp itself and positive
+-- for replies. Chirps hidden from the viewer by a block or mute are left
+-- out.
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_id, 0 AS depth FROM chirps
+    WHERE chirps.id = sqlc.arg('id')
+    UNION ALL
+    SELECT c.id, c.parent_id, a.depth - 1 FROM chirps c
+    JOIN ancestors a ON c.id = a.parent_id
+), descendants AS (
+    SELECT id, 0 AS depth FROM chirps
+    WHERE chirps.id = sqlc.arg('id')
+    UNION ALL
+    SELECT c.id, d.depth + 1 FROM chirps c
+    JOIN descendants d ON c.parent_id = d.id
+), thread AS (
+    SELECT ancestors.id, ancestors.depth FROM ancestors
+    UNION
+    SELECT descendants.id, descendants.depth FROM descendants
+)
+SELECT chirps.*, thread.depth FROM chirps
+JOIN thread ON chirps.id = thread.id
+JOIN users ON users.id = chirps.user_id
+WHERE users.deleted_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('viewer_id'))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg('viewer_id') AND mutes.muted_id = chirps.user_id
+)
+ORDER BY thread.depth, chirps.created_at;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = NOW()
+WHERE id = $1
+AND hidden_at IS NULL;
+
+-- name: UnhideChirp :exec
+UPDATE chirps
+SET hidden_at = NULL
+WHERE id = $1;
+
+-- name: GetScheduledChirps :many
+SELECT * FROM chirps
+WHERE user_id = $1
+AND status = 'scheduled'
+ORDER BY publish_at, id;
+
+-- name: CancelScheduledChirp :execrows
+-- Only deletes chirps that are still scheduled, so a cancellation that
+-- races the scheduler never removes a published chirp.
+DELETE FROM chirps
+WHERE id = $1
+AND user_id = $2
+AND status = 'scheduled';
+
+-- name: PublishScheduledChirp :one
+-- Publishes a scheduled chirp. created_at is reset so published chirps
+-- sort by when they appeared.
+UPDATE chirps
+SET status = 'published', created_at = NOW(), updated_at = NOW()
+WHERE id = $1
+AND status = 'scheduled'
+RETURNING *;
+
+-- name: GetPublishedChirpIDsByUser :many
+SELECT id FROM chirps
+WHERE user_id = $1
+AND status = 'published';
@@ -0,0 +1,70 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1
+AND followee_id = $2;
+
+-- name: DeleteFollowsBetween :exec
+-- Removes follows in both directions between two users.
+DELETE FROM follows
+WHERE (follower_id = sqlc.arg('user_id') AND followee_id = sqlc.arg('other_id'))
+OR (follower_id = sqlc.arg('other_id') AND followee_id = sqlc.arg('user_id'));
+
+-- name: GetFollowers :many
+SELECT
+    users.id,
+    users.created_at,
+    users.handle,
+    users.display_name,
+    users.bio,
+    users.avatar_url,
+    follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = sqlc.arg('user_id')
+AND users.deleted_at IS NULL
+AND (follows.created_at, users.id) < (sqlc.arg('before_created_at')::timestamp, sqlc.arg('before_id')::uuid)
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetFollowing :many
+SELECT
+    users.id,
+    users.created_at,
+    users.handle,
+    users.display_name,
+    users.bio,
+    users.avatar_url,
+    follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+AND users.deleted_at IS NULL
+AND (follows.created_at, users.id) < (sqlc.arg('before_created_at')::timestamp, sqlc.arg('before_id')::uuid)
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetHomeTimeline :many
+SELECT chirps.* FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+JOIN users ON users.id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+AND users.deleted_at IS NULL
+AND (chirps.created_at, chirps.id) < (sqlc.arg('before_created_at')::timestamp, sqlc.arg('before_id')::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg('user_id') AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('user_id'))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg('user_id') AND mutes.muted_id = chirps.user_id
+)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.arg('user_id'))
+AND chirps.status = 'published'
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
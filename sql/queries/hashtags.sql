@@ -0,0 +1,75 @@
+-- name: AddChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, tag)
+SELECT sqlc.arg('chirp_id')::uuid, unnest(sqlc.arg('tags')::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: AddChirpMentions :exec
+-- Mentions between users where either has blocked the other are dropped.
+INSERT INTO chirp_mentions (chirp_id, user_id)
+SELECT chirps.id, users.id FROM chirps, users
+WHERE chirps.id = sqlc.arg('chirp_id')
+AND users.handle = ANY(sqlc.arg('handles')::text[])
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = users.id AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = users.id)
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
+
+-- name: GetChirpsByHashtag :many
+SELECT chirps.* FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+JOIN users ON users.id = chirps.user_id
+WHERE chirp_hashtags.tag = sqlc.arg('tag')
+AND users.deleted_at IS NULL
+AND (chirps.created_at, chirps.id) < (sqlc.arg('before_created_at')::timestamp, sqlc.arg('before_id')::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('viewer_id'))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg('viewer_id') AND mutes.muted_id = chirps.user_id
+)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.arg('viewer_id'))
+AND chirps.status = 'published'
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetMentionsOfUser :many
+SELECT chirps.* FROM chirps
+JOIN chirp_mentions ON chirp_mentions.chirp_id = chirps.id
+JOIN users ON users.id = chirps.user_id
+WHERE chirp_mentions.user_id = sqlc.arg('user_id')
+AND users.deleted_at IS NULL
+AND (chirps.created_at, chirps.id) < (sqlc.arg('before_created_at')::timestamp, sqlc.arg('before_id')::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg('user_id') AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('user_id'))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg('user_id') AND mutes.muted_id = chirps.user_id
+)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.arg('user_id'))
+AND chirps.status = 'published'
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetTrendingHashtags :many
+SELECT tag, COUNT(*) AS chirp_count
+FROM chirp_hashtags
+WHERE created_at > sqlc.arg('since')::timestamp
+GROUP BY tag
+ORDER BY chirp_count DESC, tag
+LIMIT sqlc.arg('limit');
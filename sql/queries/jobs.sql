@@ -0,0 +1,56 @@
+-- name: EnqueueJob :one
+-- Returns no row if a job with the same unique_key already exists.
+INSERT INTO jobs (kind, payload, run_at, max_attempts, unique_key)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (unique_key) WHERE unique_key IS NOT NULL DO NOTHING
+RETURNING id;
+
+-- name: ClaimJobs :many
+-- Claims up to limit jobs of the given kinds that are due, or whose
+-- worker died while running them, until locked_until. Rows locked by
+-- another worker are skipped rather than waited for.
+UPDATE jobs
+SET status = 'running',
+    attempts = attempts + 1,
+    locked_until = sqlc.arg('locked_until')
+WHERE id IN (
+    SELECT id FROM jobs
+    WHERE kind = ANY(sqlc.arg('kinds')::text[])
+    AND (
+        (status = 'pending' AND run_at <= NOW())
+        OR (status = 'running' AND locked_until <= NOW())
+    )
+    ORDER BY run_at
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: DeleteJob :exec
+DELETE FROM jobs
+WHERE id = $1;
+
+-- name: RetryJob :exec
+UPDATE jobs
+SET status = 'pending',
+    run_at = $2,
+    locked_until = NULL,
+    last_error = $3
+WHERE id = $1;
+
+-- name: RescheduleJob :exec
+-- Queues the next run of a recurring job, whose attempts start over.
+UPDATE jobs
+SET status = 'pending',
+    attempts = 0,
+    run_at = $2,
+    locked_until = NULL,
+    last_error = $3
+WHERE id = $1;
+
+-- name: FailJob :exec
+UPDATE jobs
+SET status = 'failed',
+    locked_until = NULL,
+    last_error = $2
+WHERE id = $1;
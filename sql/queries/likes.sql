@@ -0,0 +1,18 @@
+-- name: LikeChirp :exec
+INSERT INTO chirp_likes (user_id, chirp_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: GetChirpLikeStats :many
+SELECT
+    chirp_id,
+    COUNT(*) AS like_count,
+    COALESCE(BOOL_OR(user_id = sqlc.arg('viewer_id')), false)::boolean AS liked_by_me
+FROM chirp_likes
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY chirp_id;
@@ -0,0 +1,38 @@
+-- name: CreateMedia :one
+INSERT INTO media (user_id, content_type, width, height, byte_size, storage_key, thumbnail_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: AttachMedia :many
+-- Attaches the user's unattached uploads to a chirp, in the order given.
+-- Media that belongs to someone else or to another chirp is skipped, so
+-- callers compare the returned IDs with the ones they asked for.
+UPDATE media
+SET chirp_id = sqlc.arg('chirp_id'), position = array_position(sqlc.arg('media_ids')::uuid[], id)
+WHERE id = ANY(sqlc.arg('media_ids')::uuid[])
+AND user_id = sqlc.arg('user_id')
+AND chirp_id IS NULL
+RETURNING id;
+
+-- name: GetMediaForChirps :many
+SELECT * FROM media
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: GetMediaByUser :many
+SELECT * FROM media
+WHERE user_id = $1;
+
+-- name: PurgeUnattachedMedia :many
+-- Deletes up to limit uploads that were never attached to a chirp and
+-- were created before cutoff, and returns their blob keys. chirp_id is
+-- checked again so that media attached meanwhile is kept.
+DELETE FROM media
+WHERE id IN (
+    SELECT id FROM media
+    WHERE chirp_id IS NULL
+    AND created_at < sqlc.arg('cutoff')::timestamptz
+    LIMIT sqlc.arg('limit')
+)
+AND chirp_id IS NULL
+RETURNING storage_key, thumbnail_key;
@@ -0,0 +1,82 @@
+-- name: CreateNotification :exec
+-- Nobody is notified about their own actions or about users they muted or
+-- have a block with, and repeating an action (like unliking and liking
+-- again) doesn't notify twice.
+INSERT INTO notifications (user_id, actor_id, type, chirp_id)
+SELECT sqlc.arg('user_id')::uuid, sqlc.arg('actor_id')::uuid, sqlc.arg('type')::text, sqlc.narg('chirp_id')::uuid
+WHERE sqlc.arg('user_id') <> sqlc.arg('actor_id')
+AND NOT EXISTS (
+    SELECT 1 FROM notifications
+    WHERE notifications.user_id = sqlc.arg('user_id')
+    AND notifications.actor_id = sqlc.arg('actor_id')
+    AND notifications.type = sqlc.arg('type')
+    AND notifications.chirp_id IS NOT DISTINCT FROM sqlc.narg('chirp_id')
+)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg('user_id') AND blocks.blocked_id = sqlc.arg('actor_id'))
+    OR (blocks.blocker_id = sqlc.arg('actor_id') AND blocks.blocked_id = sqlc.arg('user_id'))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg('user_id') AND mutes.muted_id = sqlc.arg('actor_id')
+);
+
+-- name: CreateMentionNotifications :exec
+-- Notifies every user mentioned in a chirp, except its author and users
+-- who muted the author. Mentions across a block are never indexed.
+INSERT INTO notifications (user_id, actor_id, type, chirp_id)
+SELECT chirp_mentions.user_id, chirps.user_id, 'mention', chirps.id
+FROM chirp_mentions
+JOIN chirps ON chirps.id = chirp_mentions.chirp_id
+WHERE chirp_mentions.chirp_id = $1
+AND chirp_mentions.user_id <> chirps.user_id
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = chirp_mentions.user_id AND mutes.muted_id = chirps.user_id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM notifications
+    WHERE notifications.user_id = chirp_mentions.user_id
+    AND notifications.type = 'mention'
+    AND notifications.chirp_id = chirps.id
+);
+
+-- name: GetNotifications :many
+SELECT
+    notifications.id,
+    notifications.created_at,
+    notifications.type,
+    notifications.chirp_id,
+    notifications.read_at,
+    notifications.actor_id,
+    users.handle AS actor_handle
+FROM notifications
+JOIN users ON users.id = notifications.actor_id
+WHERE notifications.user_id = sqlc.arg('user_id')
+AND users.deleted_at IS NULL
+AND (notifications.created_at, notifications.id) < (sqlc.arg('before_created_at')::timestamp, sqlc.arg('before_id')::uuid)
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CountUnreadNotifications :one
+-- Notifications from users whose accounts are being deleted aren't
+-- counted, matching GetNotifications.
+SELECT COUNT(*) FROM notifications
+JOIN users ON users.id = notifications.actor_id
+WHERE notifications.user_id = $1
+AND notifications.read_at IS NULL
+AND users.deleted_at IS NULL;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1
+AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg('user_id')
+AND id = ANY(sqlc.arg('ids')::uuid[])
+AND read_at IS NULL;
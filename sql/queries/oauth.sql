@@ -0,0 +1,31 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (user_id, name, redirect_uris, hashed_secret)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1;
+
+-- name: GetOAuthClients :many
+SELECT * FROM oauth_clients
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM oauth_clients
+WHERE id = $1
+AND user_id = $2;
+
+-- name: CreateOAuthCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, expires_at, client_id, user_id, redirect_uri, redirect_uri_supplied, scopes, code_challenge)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ConsumeOAuthCode :one
+-- Marks a code used and returns it, so it can't be exchanged twice. The
+-- caller checks the rest of the request against it.
+UPDATE oauth_authorization_codes
+SET used_at = NOW()
+WHERE code_hash = $1
+AND used_at IS NULL
+RETURNING *;
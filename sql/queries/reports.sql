@@ -0,0 +1,23 @@
+-- name: CreateReport :one
+INSERT INTO reports (chirp_id, reporter_id, reason, details)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: GetReports :many
+SELECT * FROM reports
+WHERE status = sqlc.arg('status')
+AND (created_at, id) > (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: CloseReport :one
+-- Only open reports can be closed, so closing a report twice returns no rows.
+UPDATE reports
+SET status = sqlc.arg('status'), resolved_at = NOW(), resolved_by = sqlc.arg('resolved_by')
+WHERE id = sqlc.arg('id')
+AND status = 'open'
+RETURNING *;
@@ -0,0 +1,36 @@
+-- name: SearchChirps :many
+-- Results are ordered by rank, then newest first. The (rank, created_at, id)
+-- of the last row of a page is the cursor for the next one.
+SELECT
+    chirps.id,
+    chirps.created_at,
+    chirps.updated_at,
+    chirps.body,
+    chirps.user_id,
+    chirps.parent_id,
+    chirps.kind,
+    chirps.referenced_chirp_id,
+    ts_rank(chirps.search_vector, websearch_to_tsquery('simple', sqlc.arg('query')))::real AS rank,
+    ts_headline('simple', chirps.body, websearch_to_tsquery('simple', sqlc.arg('query')), sqlc.arg('headline_options')::text) AS headline
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.search_vector @@ websearch_to_tsquery('simple', sqlc.arg('query'))
+AND users.deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id'))
+AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since'))
+AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until'))
+AND (ts_rank(chirps.search_vector, websearch_to_tsquery('simple', sqlc.arg('query')))::real, chirps.created_at, chirps.id)
+    < (sqlc.arg('before_rank')::real, sqlc.arg('before_created_at')::timestamp, sqlc.arg('before_id')::uuid)
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('viewer_id'))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg('viewer_id') AND mutes.muted_id = chirps.user_id
+)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.arg('viewer_id'))
+AND chirps.status = 'published'
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
@@ -1,26 +1,57 @@
 -- name: CreateUser :one
-INSERT INTO users (email, hashed_password)
+INSERT INTO users (email, hashed_password, handle)
 VALUES (
     $1,
-    $2
+    $2,
+    $3
     )
-RETURNING id, created_at, updated_at, email;
+RETURNING id, created_at, updated_at, email, handle;
 
 -- name: CreateChirp :one
-INSERT INTO chirps (body, user_id)
+-- A chirp with a publish_at is stored as scheduled.
+INSERT INTO chirps (body, user_id, parent_id, kind, referenced_chirp_id, publish_at, status)
 VALUES (
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    CASE WHEN $6::timestamp IS NULL THEN 'published' ELSE 'scheduled' END
     )
 RETURNING *;
 
 -- name: GetChirps :many
-SELECT * FROM chirps
-ORDER BY created_at;
+-- Skips chirps hidden from the viewer by a block or mute, and those of
+-- accounts pending deletion.
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE users.deleted_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('viewer_id'))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg('viewer_id') AND mutes.muted_id = chirps.user_id
+)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.arg('viewer_id'))
+AND chirps.status = 'published'
+ORDER BY chirps.created_at;
 
 -- name: GetChirp :one
-SELECT * FROM chirps
-WHERE id = $1;
+-- Returns the chirp unless a block separates its author and the viewer or
+-- the author's account is pending deletion.
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = sqlc.arg('id')
+AND users.deleted_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('viewer_id'))
+);
 
 -- name: GetUserByEmail :one
 SELECT * FROM users
@@ -64,4 +95,107 @@ RETURNING id, email, created_at, updated_at;
 -- name: DeleteChirp :execrows
 DELETE FROM chirps
 WHERE id = $1
-AND user_id = $2;
\ No newline at end of file
+AND user_id = $2;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET
+    email = COALESCE(sqlc.narg('email'), email),
+    hashed_password = COALESCE(sqlc.narg('hashed_password'), hashed_password),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    avatar_url = COALESCE(sqlc.narg('avatar_url'), avatar_url),
+    handle = COALESCE(sqlc.narg('handle'), handle),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING id, created_at, updated_at, email, display_name, bio, avatar_url, handle;
+
+-- name: GetPublicProfileByHandle :one
+SELECT
+    users.id,
+    users.created_at,
+    users.handle,
+    users.display_name,
+    users.bio,
+    users.avatar_url,
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = users.id AND chirps.status = 'published') AS chirp_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = users.id) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id) AS following_count
+FROM users
+WHERE users.handle = $1
+AND users.deleted_at IS NULL;
+
+-- name: GetChirpsByAuthorHandles :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE users.handle = ANY(sqlc.arg('handles')::text[])
+AND users.deleted_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = sqlc.arg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+    OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('viewer_id'))
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE mutes.muter_id = sqlc.arg('viewer_id') AND mutes.muted_id = chirps.user_id
+)
+AND (chirps.hidden_at IS NULL OR chirps.user_id = sqlc.arg('viewer_id'))
+AND chirps.status = 'published'
+ORDER BY chirps.created_at;
+
+
+-- name: GetChirpsByUserID :many
+SELECT * FROM chirps
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: SoftDeleteUser :exec
+UPDATE users
+SET
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: RestoreUser :exec
+UPDATE users
+SET
+    deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
+
+-- name: GetPurgeableUserIDs :many
+SELECT id FROM users
+WHERE deleted_at IS NOT NULL
+AND deleted_at < sqlc.arg('cutoff')::timestamptz;
+
+-- name: PurgeDeletedUser :execrows
+-- Deletes the user only if they are still past the cutoff, so an
+-- account restored since GetPurgeableUserIDs ran is kept.
+DELETE FROM users
+WHERE id = sqlc.arg('id')
+AND deleted_at IS NOT NULL
+AND deleted_at < sqlc.arg('cutoff')::timestamptz;
+
+-- name: PurgeRefreshTokens :execrows
+-- Deletes up to limit refresh tokens that expired or were revoked before
+-- revoked_before. Deleting in batches keeps each statement's locks short.
+DELETE FROM refresh_tokens
+WHERE token IN (
+    SELECT token FROM refresh_tokens
+    WHERE expires_at < NOW()
+    OR revoked_at < sqlc.arg('revoked_before')::timestamptz
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE SKIP LOCKED
+);
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+AND revoked_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,86 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (user_id, url, secret, events)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetWebhooks :many
+SELECT * FROM webhooks
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = $1
+AND user_id = $2;
+
+-- name: CountWebhooks :one
+SELECT COUNT(*) FROM webhooks
+WHERE user_id = $1;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1
+AND user_id = $2;
+
+-- name: EnqueueWebhookDeliveries :many
+-- Queues a delivery of an event to each of the user's webhooks that
+-- subscribed to its type.
+INSERT INTO webhook_deliveries (webhook_id, event_type, payload)
+SELECT webhooks.id, sqlc.arg('event_type')::text, sqlc.arg('payload')::text
+FROM webhooks
+WHERE webhooks.user_id = sqlc.arg('user_id')
+AND sqlc.arg('event_type')::text = ANY(webhooks.events)
+RETURNING id;
+
+-- name: StartWebhookDeliveryAttempt :one
+-- Counts an attempt at a pending delivery and returns what is needed to
+-- send it. No row is returned once the delivery succeeded or failed.
+UPDATE webhook_deliveries
+SET attempts = webhook_deliveries.attempts + 1,
+    last_attempt_at = NOW()
+FROM webhooks
+WHERE webhooks.id = webhook_deliveries.webhook_id
+AND webhook_deliveries.id = $1
+AND webhook_deliveries.status = 'pending'
+RETURNING
+    webhook_deliveries.id,
+    webhook_deliveries.event_type,
+    webhook_deliveries.payload,
+    webhook_deliveries.attempts,
+    webhooks.url,
+    webhooks.secret;
+
+-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+SET status = 'succeeded',
+    response_status = $2,
+    last_error = ''
+WHERE id = $1;
+
+-- name: RetryWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET next_attempt_at = $2,
+    response_status = $3,
+    last_error = $4
+WHERE id = $1;
+
+-- name: FailWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = 'failed',
+    response_status = $2,
+    last_error = $3
+WHERE id = $1;
+
+-- name: CreateWebhookDeadLetter :exec
+INSERT INTO webhook_dead_letters (delivery_id, webhook_id, event_type, payload, attempts, last_error)
+SELECT id, webhook_id, event_type, payload, attempts, last_error
+FROM webhook_deliveries
+WHERE id = $1
+ON CONFLICT (delivery_id) DO NOTHING;
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = sqlc.arg('webhook_id')
+AND (created_at, id) < (sqlc.arg('before_created_at')::timestamp, sqlc.arg('before_id')::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
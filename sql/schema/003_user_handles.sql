@@ -0,0 +1,10 @@
+-- +goose Up
+-- Handles are stored lowercased, so a plain UNIQUE constraint is enough
+-- to make them case-insensitively unique.
+ALTER TABLE users
+ADD COLUMN handle TEXT UNIQUE
+    CONSTRAINT users_handle_format CHECK (handle ~ '^[a-z0-9_]{3,30}$');
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN handle;
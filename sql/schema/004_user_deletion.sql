@@ -0,0 +1,14 @@
+-- +goose Up
+-- deleted_at marks an account as scheduled for deletion. Rows are purged
+-- by the server once the grace period has passed; chirps and refresh
+-- tokens go with them through ON DELETE CASCADE.
+ALTER TABLE users
+ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_deleted_at;
+
+ALTER TABLE users
+DROP COLUMN deleted_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- chirp_revisions keeps every body a chirp had before it was edited.
+-- created_at is when that version was written, replaced_at is when the
+-- edit replaced it.
+CREATE TABLE chirp_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    chirp_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    replaced_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_chirp
+      FOREIGN KEY(chirp_id)
+      REFERENCES chirps(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX idx_chirp_revisions_chirp_id ON chirp_revisions(chirp_id, replaced_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_revisions;
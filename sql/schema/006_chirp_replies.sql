@@ -0,0 +1,16 @@
+-- +goose Up
+-- parent_id points at the chirp this one replies to. Replies outlive a
+-- deleted parent and simply become top-level chirps.
+ALTER TABLE chirps
+ADD COLUMN parent_id UUID
+    CONSTRAINT fk_parent
+      REFERENCES chirps(id)
+      ON DELETE SET NULL;
+
+CREATE INDEX idx_chirps_parent_id ON chirps(parent_id, created_at, id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_chirps_parent_id;
+
+ALTER TABLE chirps
+DROP COLUMN parent_id;
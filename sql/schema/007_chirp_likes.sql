@@ -0,0 +1,23 @@
+-- +goose Up
+-- The primary key makes likes unique per user and chirp and also serves
+-- "has this user liked these chirps" lookups. The chirp_id index serves
+-- the like counts.
+CREATE TABLE chirp_likes (
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, chirp_id),
+    CONSTRAINT fk_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_chirp
+      FOREIGN KEY(chirp_id)
+      REFERENCES chirps(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX idx_chirp_likes_chirp_id ON chirp_likes(chirp_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_likes;
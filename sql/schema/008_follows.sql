@@ -0,0 +1,37 @@
+-- +goose Up
+-- Indexing strategy for the follow graph and the home timeline:
+--
+--   * The primary key (follower_id, followee_id) keeps follows unique and
+--     answers "who does X follow", which is the first step of building
+--     X's timeline and of the following list.
+--   * idx_follows_followee (followee_id, created_at) answers "who follows
+--     X" in the order the follower list is paginated.
+--   * idx_chirps_user_created (user_id, created_at DESC, id DESC) lets the
+--     timeline read each followed account's newest chirps straight from
+--     the index and merge them, instead of sorting every chirp those
+--     accounts ever wrote. It matches the (created_at, id) keyset used by
+--     cursor pagination.
+CREATE TABLE follows (
+    follower_id UUID NOT NULL,
+    followee_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (follower_id, followee_id),
+    CONSTRAINT no_self_follow CHECK (follower_id <> followee_id),
+    CONSTRAINT fk_follower
+      FOREIGN KEY(follower_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_followee
+      FOREIGN KEY(followee_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX idx_follows_followee ON follows(followee_id, created_at);
+
+CREATE INDEX idx_chirps_user_created ON chirps(user_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_chirps_user_created;
+
+DROP TABLE IF EXISTS follows;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- kind tells plain chirps apart from rechirps (a repost without a body)
+-- and quotes (a chirp with its own body that embeds another chirp).
+-- Rechirps and quotes are removed together with the chirp they reference.
+ALTER TABLE chirps
+ADD COLUMN kind TEXT NOT NULL DEFAULT 'chirp',
+ADD COLUMN referenced_chirp_id UUID
+    CONSTRAINT fk_referenced_chirp
+      REFERENCES chirps(id)
+      ON DELETE CASCADE,
+ADD CONSTRAINT chirps_kind_reference CHECK (
+    (kind = 'chirp' AND referenced_chirp_id IS NULL)
+    OR (kind IN ('rechirp', 'quote') AND referenced_chirp_id IS NOT NULL)
+);
+
+-- A user can rechirp a given chirp only once.
+CREATE UNIQUE INDEX idx_chirps_unique_rechirp ON chirps(user_id, referenced_chirp_id)
+WHERE kind = 'rechirp';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_chirps_unique_rechirp;
+
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_kind_reference,
+DROP COLUMN referenced_chirp_id,
+DROP COLUMN kind;
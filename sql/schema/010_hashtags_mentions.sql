@@ -0,0 +1,40 @@
+-- +goose Up
+-- Hashtags and mentions are extracted from chirp bodies when a chirp is
+-- written and indexed here so they can be looked up without scanning
+-- chirp text.
+CREATE TABLE chirp_hashtags (
+    chirp_id UUID NOT NULL,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chirp_id, tag),
+    CONSTRAINT fk_chirp
+      FOREIGN KEY(chirp_id)
+      REFERENCES chirps(id)
+      ON DELETE CASCADE
+);
+
+-- Serves both the per-tag chirp listing and the trending window scan.
+CREATE INDEX idx_chirp_hashtags_tag_created ON chirp_hashtags(tag, created_at DESC);
+CREATE INDEX idx_chirp_hashtags_created ON chirp_hashtags(created_at);
+
+CREATE TABLE chirp_mentions (
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chirp_id, user_id),
+    CONSTRAINT fk_chirp
+      FOREIGN KEY(chirp_id)
+      REFERENCES chirps(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX idx_chirp_mentions_user_id ON chirp_mentions(user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_mentions;
+
+DROP TABLE IF EXISTS chirp_hashtags;
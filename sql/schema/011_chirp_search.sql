@@ -0,0 +1,15 @@
+-- +goose Up
+-- search_vector is kept in sync with body by Postgres itself. The 'simple'
+-- configuration lowercases words without language specific stemming, so
+-- it works the same for chirps in any language.
+ALTER TABLE chirps
+ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', body)) STORED;
+
+CREATE INDEX idx_chirps_search_vector ON chirps USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_chirps_search_vector;
+
+ALTER TABLE chirps
+DROP COLUMN search_vector;
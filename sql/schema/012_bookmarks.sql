@@ -0,0 +1,23 @@
+-- +goose Up
+-- Bookmarks are private to the user who made them. The primary key makes
+-- them unique per user and chirp; the (user_id, created_at) index serves
+-- the newest-first bookmark list.
+CREATE TABLE bookmarks (
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, chirp_id),
+    CONSTRAINT fk_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_chirp
+      FOREIGN KEY(chirp_id)
+      REFERENCES chirps(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX idx_bookmarks_user_created ON bookmarks(user_id, created_at DESC, chirp_id DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS bookmarks;
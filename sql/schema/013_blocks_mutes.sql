@@ -0,0 +1,44 @@
+-- +goose Up
+-- A block hides both users' chirps from each other and stops them from
+-- interacting. A mute only hides the muted user's chirps from the muter.
+--
+-- The primary keys answer "has X blocked/muted Y" and list a user's own
+-- blocks and mutes. idx_blocks_blocked answers the reverse question,
+-- "who has blocked Y", needed because blocks apply in both directions.
+CREATE TABLE blocks (
+    blocker_id UUID NOT NULL,
+    blocked_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CONSTRAINT no_self_block CHECK (blocker_id <> blocked_id),
+    CONSTRAINT fk_blocker
+      FOREIGN KEY(blocker_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_blocked
+      FOREIGN KEY(blocked_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX idx_blocks_blocked ON blocks(blocked_id);
+
+CREATE TABLE mutes (
+    muter_id UUID NOT NULL,
+    muted_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (muter_id, muted_id),
+    CONSTRAINT no_self_mute CHECK (muter_id <> muted_id),
+    CONSTRAINT fk_muter
+      FOREIGN KEY(muter_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_muted
+      FOREIGN KEY(muted_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS mutes;
+DROP TABLE IF EXISTS blocks;
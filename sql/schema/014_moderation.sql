@@ -0,0 +1,52 @@
+-- +goose Up
+-- Roles are granted directly in the database, e.g.
+--   UPDATE users SET role = 'moderator' WHERE handle = '...';
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+    CONSTRAINT valid_role CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- A hidden chirp is left out of public listings but is still shown to its
+-- author. Moderators can unhide it by clearing hidden_at.
+ALTER TABLE chirps
+ADD COLUMN hidden_at TIMESTAMP;
+
+CREATE TABLE reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    chirp_id UUID NOT NULL,
+    reporter_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    resolved_at TIMESTAMP,
+    resolved_by UUID,
+    CONSTRAINT valid_reason CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'self_harm', 'misinformation', 'other')),
+    CONSTRAINT valid_status CHECK (status IN ('open', 'resolved', 'dismissed')),
+    CONSTRAINT fk_chirp
+      FOREIGN KEY(chirp_id)
+      REFERENCES chirps(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_reporter
+      FOREIGN KEY(reporter_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_resolved_by
+      FOREIGN KEY(resolved_by)
+      REFERENCES users(id)
+      ON DELETE SET NULL
+);
+
+-- A user can have only one open report per chirp.
+CREATE UNIQUE INDEX idx_reports_open_per_reporter ON reports(chirp_id, reporter_id) WHERE status = 'open';
+
+-- Serves the moderation queue, which is read oldest first by status.
+CREATE INDEX idx_reports_status_created ON reports(status, created_at, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE chirps
+DROP COLUMN hidden_at;
+
+ALTER TABLE users
+DROP COLUMN role;
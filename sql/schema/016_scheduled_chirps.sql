@@ -0,0 +1,17 @@
+-- +goose Up
+-- A scheduled chirp is stored right away but stays out of every listing
+-- until publish_at, when the scheduler marks it published.
+ALTER TABLE chirps
+ADD COLUMN status TEXT NOT NULL DEFAULT 'published'
+    CONSTRAINT valid_chirp_status CHECK (status IN ('scheduled', 'published')),
+ADD COLUMN publish_at TIMESTAMP,
+ADD CONSTRAINT scheduled_has_publish_at CHECK (status <> 'scheduled' OR publish_at IS NOT NULL);
+
+-- Lets the scheduler find due chirps without scanning published ones.
+CREATE INDEX idx_chirps_scheduled ON chirps(publish_at) WHERE status = 'scheduled';
+
+-- +goose Down
+ALTER TABLE chirps
+DROP CONSTRAINT scheduled_has_publish_at,
+DROP COLUMN publish_at,
+DROP COLUMN status;
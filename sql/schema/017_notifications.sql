@@ -0,0 +1,32 @@
+-- +goose Up
+-- A notification tells user_id that actor_id did something involving
+-- them. chirp_id is the chirp liked, the reply or the chirp mentioning
+-- the user, and NULL for follows.
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    type TEXT NOT NULL,
+    chirp_id UUID,
+    read_at TIMESTAMP,
+    CONSTRAINT valid_type CHECK (type IN ('like', 'reply', 'mention', 'follow')),
+    CONSTRAINT fk_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_actor
+      FOREIGN KEY(actor_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_chirp
+      FOREIGN KEY(chirp_id)
+      REFERENCES chirps(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX idx_notifications_user_created ON notifications(user_id, created_at DESC, id DESC);
+CREATE INDEX idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS notifications;
@@ -0,0 +1,69 @@
+-- +goose Up
+-- A webhook receives the events listed in events that concern its
+-- owner's chirps. secret signs every delivery.
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    CONSTRAINT fk_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhooks_user ON webhooks(user_id);
+
+-- A webhook delivery is one event sent to one webhook. Pending deliveries
+-- are retried at next_attempt_at until they succeed or run out of
+-- attempts, when they are marked failed and copied to the dead letters.
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    webhook_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_attempt_at TIMESTAMP,
+    response_status INTEGER,
+    last_error TEXT NOT NULL DEFAULT '',
+    CONSTRAINT valid_status CHECK (status IN ('pending', 'succeeded', 'failed')),
+    CONSTRAINT fk_webhook
+      FOREIGN KEY(webhook_id)
+      REFERENCES webhooks(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook_created ON webhook_deliveries(webhook_id, created_at DESC, id DESC);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+-- Dead letters keep deliveries that failed for good, so they can be
+-- inspected and replayed by hand.
+CREATE TABLE webhook_dead_letters (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivery_id UUID NOT NULL UNIQUE,
+    webhook_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    last_error TEXT NOT NULL,
+    CONSTRAINT fk_delivery
+      FOREIGN KEY(delivery_id)
+      REFERENCES webhook_deliveries(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_webhook
+      FOREIGN KEY(webhook_id)
+      REFERENCES webhooks(id)
+      ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS webhook_dead_letters;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,33 @@
+-- +goose Up
+-- A job is a unit of background work run by the worker in internal/jobs.
+-- Pending jobs run once run_at has passed. A running job whose
+-- locked_until has passed belonged to a worker that died, and is run
+-- again. Jobs are deleted when they succeed and kept as failed once they
+-- run out of attempts.
+CREATE TABLE jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    kind TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    run_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    CONSTRAINT valid_status CHECK (status IN ('pending', 'running', 'failed')),
+    CONSTRAINT running_has_lock CHECK (status <> 'running' OR locked_until IS NOT NULL)
+);
+
+CREATE INDEX idx_jobs_pending ON jobs(run_at) WHERE status = 'pending';
+CREATE INDEX idx_jobs_running ON jobs(locked_until) WHERE status = 'running';
+
+-- Scheduled chirps used to be published by polling; they are now
+-- published by a job each.
+INSERT INTO jobs (kind, payload, run_at, max_attempts)
+SELECT 'chirp.publish', json_build_object('chirp_id', id)::text, publish_at, 5
+FROM chirps
+WHERE status = 'scheduled';
+
+-- +goose Down
+DROP TABLE IF EXISTS jobs;
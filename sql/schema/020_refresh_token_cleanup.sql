@@ -0,0 +1,9 @@
+-- +goose Up
+-- Let the refresh token janitor find expired and revoked tokens without
+-- scanning the table.
+CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+CREATE INDEX idx_refresh_tokens_revoked_at ON refresh_tokens(revoked_at) WHERE revoked_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_refresh_tokens_revoked_at;
+DROP INDEX IF EXISTS idx_refresh_tokens_expires_at;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- An API key lets a program act as its owner within scopes. Only a hash
+-- of the key is stored; prefix is the start of the key, kept so users can
+-- tell their keys apart.
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    hashed_key TEXT NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    CONSTRAINT fk_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX idx_api_keys_user ON api_keys(user_id) WHERE revoked_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS api_keys;
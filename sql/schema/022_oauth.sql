@@ -0,0 +1,48 @@
+-- +goose Up
+-- An OAuth client is a third-party app registered by user_id. Its id is
+-- the client_id. Confidential clients, which can keep a secret, have a
+-- hashed_secret; public clients rely on PKCE alone.
+CREATE TABLE oauth_clients (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    redirect_uris TEXT[] NOT NULL,
+    hashed_secret TEXT,
+    CONSTRAINT fk_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX idx_oauth_clients_user ON oauth_clients(user_id);
+
+-- An authorization code is issued when user_id approves a client's
+-- request for scopes and is exchanged once for an access token. Only a
+-- hash of the code is stored. redirect_uri_supplied records whether the
+-- client sent redirect_uri, which it then has to repeat in the token
+-- request.
+CREATE TABLE oauth_authorization_codes (
+    code_hash TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    client_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    redirect_uri TEXT NOT NULL,
+    redirect_uri_supplied BOOLEAN NOT NULL,
+    scopes TEXT[] NOT NULL,
+    code_challenge TEXT NOT NULL,
+    used_at TIMESTAMP,
+    CONSTRAINT fk_client
+      FOREIGN KEY(client_id)
+      REFERENCES oauth_clients(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_clients;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- A job with a unique_key is only enqueued if no other job has the same
+-- key. Recurring jobs are keyed by their kind, so that every instance can
+-- enqueue them at startup and only one of each is queued.
+ALTER TABLE jobs ADD COLUMN unique_key TEXT;
+
+CREATE UNIQUE INDEX idx_jobs_unique_key ON jobs(unique_key) WHERE unique_key IS NOT NULL;
+
+-- Webhook deliveries used to be sent by polling; each attempt is now a
+-- job.
+INSERT INTO jobs (kind, payload, run_at, max_attempts)
+SELECT 'webhook.deliver', json_build_object('delivery_id', id)::text, next_attempt_at, 5
+FROM webhook_deliveries
+WHERE status = 'pending';
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+
+-- +goose Down
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+DELETE FROM jobs WHERE kind = 'webhook.deliver' OR unique_key IS NOT NULL;
+DROP INDEX IF EXISTS idx_jobs_unique_key;
+ALTER TABLE jobs DROP COLUMN unique_key;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Serves the purge of uploads that were never attached to a chirp.
+CREATE INDEX idx_media_unattached ON media(created_at) WHERE chirp_id IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_media_unattached;
+DELETE FROM jobs WHERE kind = 'media.purge';
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/BabichevDima/goServer/internal/jobs"
+	"github.com/google/uuid"
+)
+
+const (
+	// tokenPurgeInterval is how often purgeRefreshTokensJob runs.
+	tokenPurgeInterval = time.Hour
+	// tokenPurgeBatchSize is how many refresh tokens are deleted per
+	// statement.
+	tokenPurgeBatchSize = 1000
+	// defaultRevokedTokenRetention is how long revoked refresh tokens are
+	// kept, so that attempts to use them can still be told apart from
+	// unknown tokens.
+	defaultRevokedTokenRetention = 7 * 24 * time.Hour
+)
+
+// tokenPurgeStats counts the work of the refresh token janitor since the
+// server started.
+type tokenPurgeStats struct {
+	runs        atomic.Int64
+	purged      atomic.Int64
+	lastPurged  atomic.Int64
+	lastRunUnix atomic.Int64
+}
+
+// purgeRefreshTokensJob deletes expired refresh tokens and those revoked
+// longer ago than cfg.revokedTokenRetention. It recurs every
+// tokenPurgeInterval.
+const purgeRefreshTokensJob = jobs.Kind[struct{}]("refresh_tokens.purge")
+
+// runRefreshTokenPurge runs purgeRefreshTokensJob.
+func (cfg *apiConfig) runRefreshTokenPurge(ctx context.Context) error {
+	purged, err := cfg.purgeRefreshTokens(ctx)
+	if purged > 0 {
+		log.Printf("Purged %d refresh tokens", purged)
+	}
+	return err
+}
+
+// purgeRefreshTokens deletes stale refresh tokens in batches until none
+// are left and returns how many it deleted, including when it fails
+// part way.
+func (cfg *apiConfig) purgeRefreshTokens(ctx context.Context) (int64, error) {
+	revokedBefore := time.Now().Add(-cfg.revokedTokenRetention)
+
+	var total int64
+	defer func() {
+		cfg.tokenPurges.runs.Add(1)
+		cfg.tokenPurges.purged.Add(total)
+		cfg.tokenPurges.lastPurged.Store(total)
+		cfg.tokenPurges.lastRunUnix.Store(time.Now().Unix())
+	}()
+
+	for {
+		purged, err := cfg.DB.PurgeRefreshTokens(ctx, database.PurgeRefreshTokensParams{
+			RevokedBefore: revokedBefore,
+			Limit:         tokenPurgeBatchSize,
+		})
+		total += purged
+		if err != nil {
+			return total, err
+		}
+		if purged < tokenPurgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// handlerPurgeRefreshTokens runs the refresh token janitor right away and
+// returns how many tokens it deleted along with its running totals.
+func (cfg *apiConfig) handlerPurgeRefreshTokens(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	type response struct {
+		Purged      int64 `json:"purged"`
+		TotalPurged int64 `json:"total_purged"`
+		Runs        int64 `json:"runs"`
+	}
+
+	purged, err := cfg.purgeRefreshTokens(r.Context())
+	if err != nil {
+		respondWithAPIError(w, dbError(err, "Failed to purge refresh tokens"))
+		return
+	}
+	log.Printf("User %s purged %d refresh tokens", userID, purged)
+
+	respondWithJSON(w, http.StatusOK, response{
+		Purged:      purged,
+		TotalPurged: cfg.tokenPurges.purged.Load(),
+		Runs:        cfg.tokenPurges.runs.Load(),
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/BabichevDima/goServer/internal/database"
+	"github.com/BabichevDima/goServer/internal/jobs"
+	"github.com/BabichevDima/goServer/internal/webhook"
+	"github.com/google/uuid"
+)
+
+// maxWebhookAttempts is how many times a delivery is attempted before it
+// is moved to the dead letters. With webhook.Backoff, the last attempt
+// happens about 4 hours and 15 minutes after the first.
+const maxWebhookAttempts = 10
+
+// Statuses of webhook deliveries, stored in webhook_deliveries.status.
+const (
+	webhookDeliveryPending   = "pending"
+	webhookDeliverySucceeded = "succeeded"
+	webhookDeliveryFailed    = "failed"
+)
+
+// deliverWebhookJob makes one attempt at a webhook delivery. A job is
+// enqueued with each delivery and again after each failed attempt, so
+// that retries follow webhook.Backoff and end in the dead letters rather
+// than following the job queue's own retries.
+const deliverWebhookJob = jobs.Kind[deliverWebhookPayload]("webhook.deliver")
+
+type deliverWebhookPayload struct {
+	DeliveryID uuid.UUID `json:"delivery_id"`
+}
+
+// enqueueWebhookAttempt schedules an attempt at a delivery as part of the
+// transaction that queues or retries it.
+func enqueueWebhookAttempt(ctx context.Context, q *database.Queries, deliveryID uuid.UUID, runAt time.Time) error {
+	_, err := deliverWebhookJob.Enqueue(ctx, q, deliverWebhookPayload{DeliveryID: deliveryID}, jobs.EnqueueOptions{
+		RunAt: runAt,
+	})
+	return err
+}
+
+// deliverWebhook sends a pending delivery and records the outcome. The
+// job only fails if the outcome can't be recorded, in which case it is
+// retried and makes another attempt.
+func (cfg *apiConfig) deliverWebhook(ctx context.Context, payload deliverWebhookPayload) error {
+	delivery, err := cfg.DB.StartWebhookDeliveryAttempt(ctx, payload.DeliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Already sent, failed for good or deleted with its webhook.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	status, err := cfg.webhooks.Send(ctx, webhook.Delivery{
+		ID:        delivery.ID.String(),
+		URL:       delivery.Url,
+		Secret:    delivery.Secret,
+		EventType: delivery.EventType,
+		Body:      []byte(delivery.Payload),
+	})
+	return cfg.recordWebhookAttempt(ctx, delivery, status, err)
+}
+
+// recordWebhookAttempt marks a delivery succeeded, schedules its next
+// attempt or, once it is out of attempts, marks it failed and adds it to
+// the dead letters.
+func (cfg *apiConfig) recordWebhookAttempt(ctx context.Context, delivery database.StartWebhookDeliveryAttemptRow, status int, sendErr error) error {
+	responseStatus := sql.NullInt32{Int32: int32(status), Valid: status != 0}
+	if sendErr == nil {
+		return cfg.DB.MarkWebhookDeliverySucceeded(ctx, database.MarkWebhookDeliverySucceededParams{
+			ID:             delivery.ID,
+			ResponseStatus: responseStatus,
+		})
+	}
+
+	if delivery.Attempts < maxWebhookAttempts {
+		nextAttemptAt := time.Now().Add(webhook.Backoff(int(delivery.Attempts)))
+		return cfg.withTx(ctx, func(q *database.Queries) error {
+			err := q.RetryWebhookDelivery(ctx, database.RetryWebhookDeliveryParams{
+				ID:             delivery.ID,
+				NextAttemptAt:  nextAttemptAt,
+				ResponseStatus: responseStatus,
+				LastError:      sendErr.Error(),
+			})
+			if err != nil {
+				return err
+			}
+			return enqueueWebhookAttempt(ctx, q, delivery.ID, nextAttemptAt)
+		})
+	}
+
+	log.Printf("Webhook delivery %s failed after %d attempts: %v", delivery.ID, delivery.Attempts, sendErr)
+	return cfg.withTx(ctx, func(q *database.Queries) error {
+		err := q.FailWebhookDelivery(ctx, database.FailWebhookDeliveryParams{
+			ID:             delivery.ID,
+			ResponseStatus: responseStatus,
+			LastError:      sendErr.Error(),
+		})
+		if err != nil {
+			return err
+		}
+		return q.CreateWebhookDeadLetter(ctx, delivery.ID)
+	})
+}